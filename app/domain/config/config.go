@@ -6,7 +6,10 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -36,6 +39,88 @@ type App struct {
 
 	// Keys of Engines
 	EngineNames []string `yaml:"engineNames"`
+
+	// Restart is configuration of restarting crashed engines.
+	Restart Restart `yaml:"restart"`
+
+	// Idle is configuration of closing idle engines.
+	Idle Idle `yaml:"idle"`
+
+	// Health is configuration of health checks of engines.
+	Health Health `yaml:"health"`
+}
+
+// Restart is configuration of restarting crashed engines.
+// The engine is restarted with the same options and the current position.
+type Restart struct {
+	// Enabled is true if crashed engines are restarted automatically.
+	Enabled bool `yaml:"enabled"`
+
+	// MaxRestarts is the max count of restarts of each engine within Window.
+	// When exceeded, the engine is left crashed.
+	MaxRestarts int `yaml:"maxRestarts"`
+
+	// Window is the duration MaxRestarts is counted in, e.g. 10m.
+	Window time.Duration `yaml:"window"`
+}
+
+// Idle is configuration of closing idle engines. An engine is idle while
+// it is neither accessed via API nor thinking, and no stream of its events
+// or result is open.
+type Idle struct {
+	// Timeout is the duration idle engines are closed after, e.g. 30m.
+	// 0 means idle engines are never closed.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Engines overrides Timeout for each engine.
+	// The key is the name of shogi engine in Engines.
+	Engines map[string]time.Duration `yaml:"engines"`
+}
+
+// TimeoutOf returns the idle timeout of the engine.
+func (i Idle) TimeoutOf(name string) time.Duration {
+	if t, ok := i.Engines[name]; ok {
+		return t
+	}
+	return i.Timeout
+}
+
+// Health is configuration of health checks of engines. Engines are
+// checked by 'isready' periodically while they are not thinking.
+type Health struct {
+	// Interval is the interval of the checks, e.g. 30s.
+	// 0 means the checks are disabled.
+	Interval time.Duration `yaml:"interval"`
+
+	// Timeout is the max time to wait for 'readyok'.
+	// It must be positive when the checks are enabled.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// MaxFailures is the count of consecutive failures
+	// the engine is marked unhealthy after. It must be 1 or more
+	// when the checks are enabled.
+	MaxFailures int `yaml:"maxFailures"`
+
+	// Restart is true if unhealthy engines are killed, so that they are
+	// restarted like crashed engines. Restart.Enabled must be true too.
+	Restart bool `yaml:"restart"`
+}
+
+// validate returns error if the health checks are enabled with invalid values.
+func (h Health) validate(restart Restart) error {
+	if h.Interval <= 0 {
+		return nil
+	}
+	if h.Timeout <= 0 {
+		return fmt.Errorf("health.timeout must be positive. timeout=%s", h.Timeout)
+	}
+	if h.MaxFailures < 1 {
+		return fmt.Errorf("health.maxFailures must be 1 or more. maxFailures=%d", h.MaxFailures)
+	}
+	if h.Restart && !restart.Enabled {
+		return errors.New("health.restart requires restart.enabled")
+	}
+	return nil
 }
 
 // New returns new Config.
@@ -84,6 +169,10 @@ func New(appPath, logPath string) *Config {
 		panic("Engines is empty. You must specify at least one shogi engine.")
 	}
 
+	if err := app.Health.validate(app.Restart); err != nil {
+		panic(err)
+	}
+
 	keys := make([]string, len(app.Engines))
 	i := 0
 	for k := range app.Engines {
@@ -8,7 +8,9 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/murosan/goutils/testutils"
 )
@@ -40,6 +42,45 @@ func TestNew(t *testing.T) {
 				EngineNames: []string{"com"},
 			},
 		},
+		{
+			path.Join(pwd, dataDir, "app_restart.config.yml"),
+			"",
+			App{
+				Engines:     map[string]string{"com": "/home/user/path/to/engine/bin"},
+				EngineNames: []string{"com"},
+				Restart:     Restart{Enabled: true, MaxRestarts: 3, Window: 10 * time.Minute},
+			},
+		},
+		{
+			path.Join(pwd, dataDir, "app_idle.config.yml"),
+			"",
+			App{
+				Engines: map[string]string{
+					"com":  "/home/user/path/to/engine/bin",
+					"com2": "/home/user/path/to/engine2/bin",
+				},
+				EngineNames: []string{"com", "com2"},
+				Idle: Idle{
+					Timeout: 30 * time.Minute,
+					Engines: map[string]time.Duration{"com2": 0},
+				},
+			},
+		},
+		{
+			path.Join(pwd, dataDir, "app_health.config.yml"),
+			"",
+			App{
+				Engines:     map[string]string{"com": "/home/user/path/to/engine/bin"},
+				EngineNames: []string{"com"},
+				Restart:     Restart{Enabled: true, MaxRestarts: 3, Window: 10 * time.Minute},
+				Health: Health{
+					Interval:    30 * time.Second,
+					Timeout:     5 * time.Second,
+					MaxFailures: 3,
+					Restart:     true,
+				},
+			},
+		},
 	}
 
 	for i, c := range cases {
@@ -55,12 +96,48 @@ Actual:   %v
 `, key, i, expected, actual)
 		}
 
+		sort.Strings(conf.App.EngineNames)
 		if !reflect.DeepEqual(conf.App.EngineNames, c.app.EngineNames) {
 			failed("EngineNames", c.app.EngineNames, conf.App.EngineNames)
 		}
 		if !reflect.DeepEqual(conf.App.Engines, c.app.Engines) {
 			failed("Engines", c.app.Engines, conf.App.Engines)
 		}
+		if conf.App.Restart != c.app.Restart {
+			failed("Restart", c.app.Restart, conf.App.Restart)
+		}
+		if !reflect.DeepEqual(conf.App.Idle, c.app.Idle) {
+			failed("Idle", c.app.Idle, conf.App.Idle)
+		}
+		if conf.App.Health != c.app.Health {
+			failed("Health", c.app.Health, conf.App.Health)
+		}
+	}
+}
+
+func TestIdle_TimeoutOf(t *testing.T) {
+	idle := Idle{
+		Timeout: 30 * time.Minute,
+		Engines: map[string]time.Duration{"com2": 0, "com3": time.Hour},
+	}
+	cases := []struct {
+		name string
+		want time.Duration
+	}{
+		{"com", 30 * time.Minute},
+		{"com2", 0},
+		{"com3", time.Hour},
+	}
+
+	for i, c := range cases {
+		if res := idle.TimeoutOf(c.name); res != c.want {
+			t.Errorf(`
+[app > config > Idle.TimeoutOf]
+Index:    %d
+Expected: %v
+Actual:   %v
+`, i, c.want, res)
+		}
 	}
 }
 
@@ -85,6 +162,18 @@ func TestNew2(t *testing.T) {
 			path.Join(pwd, dataDir),
 			path.Join(pwd, dataDir, "log.config.yml"),
 		},
+		{
+			path.Join(pwd, dataDir, "app_health_no_timeout.config.yml"),
+			"",
+		},
+		{
+			path.Join(pwd, dataDir, "app_health_no_failures.config.yml"),
+			"",
+		},
+		{
+			path.Join(pwd, dataDir, "app_health_no_restart.config.yml"),
+			"",
+		},
 		{
 			path.Join(pwd, dataDir, "app.config.yml"),
 			path.Join(pwd, dataDir),
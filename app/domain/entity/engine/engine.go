@@ -7,6 +7,9 @@ package engine
 import (
 	"fmt"
 	"sync"
+	"time"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
 )
 
 // ID represents an id of shogi engine.
@@ -34,6 +37,37 @@ type Engine struct {
 	// Engine state.
 	state State
 
+	// Limits of the current (or last) search. Nil means infinite.
+	searchLimit *usi.SearchLimit
+
+	// True while the current (or last) search is a mate search.
+	mating bool
+
+	// The number of 'stop' commands whose 'bestmove' has not been received yet.
+	// While this is positive, outputs from the engine belong to the old search.
+	pendingStops int
+
+	// The last crash of the engine process. Nil if never crashed.
+	crash *Crash
+
+	// Times the engine was restarted after crashes, in the restart window.
+	restartedAt []time.Time
+
+	// The duration restarts are counted in. See config.Restart.
+	restartWindow time.Duration
+
+	// The last time the engine was accessed via API.
+	lastAccessedAt time.Time
+
+	// The last time the engine was thinking.
+	lastThinkingAt time.Time
+
+	// The number of open streams of the engine's events or result.
+	watchers int
+
+	// The result of the health checks. Nil until connected.
+	health *Health
+
 	// Shogi engine external command path. The path written in
 	// app config is used. It must be executable.
 	// See app/config/config.go.
@@ -42,13 +76,16 @@ type Engine struct {
 
 // New creates new Engine and returns it.
 func New(id ID, path string) *Engine {
+	now := time.Now()
 	return &Engine{
-		id:      id,
-		name:    "",
-		author:  "",
-		options: NewOptions(),
-		state:   NotConnected,
-		path:    path,
+		id:             id,
+		name:           "",
+		author:         "",
+		options:        NewOptions(),
+		state:          NotConnected,
+		lastAccessedAt: now,
+		lastThinkingAt: now,
+		path:           path,
 	}
 }
 
@@ -100,6 +137,190 @@ func (e *Engine) SetState(state State) {
 	e.Unlock()
 }
 
+func (e *Engine) GetSearchLimit() *usi.SearchLimit {
+	e.RLock()
+	defer e.RUnlock()
+	return e.searchLimit
+}
+
+func (e *Engine) SetSearchLimit(limit *usi.SearchLimit) {
+	e.Lock()
+	e.searchLimit = limit
+	e.Unlock()
+}
+
+func (e *Engine) IsMating() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return e.mating
+}
+
+func (e *Engine) SetMating(mating bool) {
+	e.Lock()
+	e.mating = mating
+	e.Unlock()
+}
+
+// AddPendingStop records that 'stop' has been sent to the engine.
+func (e *Engine) AddPendingStop() {
+	e.Lock()
+	e.pendingStops++
+	e.Unlock()
+}
+
+// DonePendingStop records that 'bestmove' for the 'stop' has been received.
+func (e *Engine) DonePendingStop() {
+	e.Lock()
+	if e.pendingStops > 0 {
+		e.pendingStops--
+	}
+	e.Unlock()
+}
+
+// HasPendingStop returns true if the engine has not acknowledged the 'stop' yet.
+func (e *Engine) HasPendingStop() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return e.pendingStops > 0
+}
+
+// ClearPendingStops forgets 'stop' commands, for example when the process has exited.
+func (e *Engine) ClearPendingStops() {
+	e.Lock()
+	e.pendingStops = 0
+	e.Unlock()
+}
+
+func (e *Engine) GetCrash() *Crash {
+	e.RLock()
+	defer e.RUnlock()
+	return e.crash
+}
+
+func (e *Engine) SetCrash(crash *Crash) {
+	e.Lock()
+	e.crash = crash
+	e.Unlock()
+}
+
+// SetRestartWindow sets the duration restarts are counted in.
+func (e *Engine) SetRestartWindow(window time.Duration) {
+	e.Lock()
+	e.restartWindow = window
+	e.Unlock()
+}
+
+// AddRestart records that the engine was restarted at the time.
+// The restarts out of the window are forgotten.
+func (e *Engine) AddRestart(at time.Time) {
+	e.Lock()
+	e.restartedAt = append(e.restartedAt, at)
+	e.pruneRestarts(at)
+	e.Unlock()
+}
+
+// CountRestarts returns the count of restarts in the window before the time.
+// The restarts out of the window are forgotten.
+func (e *Engine) CountRestarts(at time.Time) int {
+	e.Lock()
+	defer e.Unlock()
+	e.pruneRestarts(at)
+	return len(e.restartedAt)
+}
+
+// pruneRestarts removes the restarts out of the window before the time.
+// The lock must be held.
+func (e *Engine) pruneRestarts(at time.Time) {
+	e.restartedAt = e.restartedAt[e.restartsBefore(at):]
+}
+
+// restartsBefore returns the count of restarts out of the window before
+// the time. The restarts are sorted by time. The lock must be held.
+func (e *Engine) restartsBefore(at time.Time) int {
+	since := at.Add(-e.restartWindow)
+	n := 0
+	for n < len(e.restartedAt) && !e.restartedAt[n].After(since) {
+		n++
+	}
+	return n
+}
+
+// Access records that the engine was accessed via API at the time.
+func (e *Engine) Access(at time.Time) {
+	e.Lock()
+	e.lastAccessedAt = at
+	e.Unlock()
+}
+
+// Think records that the engine was thinking at the time.
+func (e *Engine) Think(at time.Time) {
+	e.Lock()
+	e.lastThinkingAt = at
+	e.Unlock()
+}
+
+// Watch records that a stream of the engine has been opened.
+func (e *Engine) Watch() {
+	e.Lock()
+	e.watchers++
+	e.Unlock()
+}
+
+// Unwatch records that a stream of the engine has been closed at the time.
+// The engine is regarded as accessed then.
+func (e *Engine) Unwatch(at time.Time) {
+	e.Lock()
+	if e.watchers > 0 {
+		e.watchers--
+	}
+	e.lastAccessedAt = at
+	e.Unlock()
+}
+
+// IsWatched returns true while any stream of the engine is open.
+func (e *Engine) IsWatched() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return e.watchers > 0
+}
+
+// IdleSince returns the time the engine has been idle since, which is the
+// later of the last API access and the last thinking activity.
+func (e *Engine) IdleSince() time.Time {
+	e.RLock()
+	defer e.RUnlock()
+	if e.lastAccessedAt.After(e.lastThinkingAt) {
+		return e.lastAccessedAt
+	}
+	return e.lastThinkingAt
+}
+
+func (e *Engine) GetHealth() *Health {
+	e.RLock()
+	defer e.RUnlock()
+	return e.health
+}
+
+func (e *Engine) SetHealth(health *Health) {
+	e.Lock()
+	e.health = health
+	e.Unlock()
+}
+
+// GetStatus returns the summary of the engine.
+func (e *Engine) GetStatus() *Status {
+	e.RLock()
+	defer e.RUnlock()
+	return &Status{
+		ID:       e.id,
+		Name:     e.name,
+		State:    e.state,
+		Restarts: len(e.restartedAt) - e.restartsBefore(time.Now()),
+		Crash:    e.crash,
+		Health:   e.health,
+	}
+}
+
 func (e *Engine) GetOptions() *Options {
 	e.RLock()
 	defer e.RUnlock()
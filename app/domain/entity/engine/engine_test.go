@@ -0,0 +1,66 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEngine_CountRestarts(t *testing.T) {
+	e := New("test", "/path/to/engine")
+	e.SetRestartWindow(10 * time.Minute)
+
+	now := time.Now()
+	e.AddRestart(now.Add(-30 * time.Minute))
+	e.AddRestart(now.Add(-20 * time.Minute))
+	e.AddRestart(now.Add(-5 * time.Minute))
+
+	// the restarts out of the window are forgotten on adding
+	if n := len(e.restartedAt); n != 1 {
+		t.Errorf("[Engine.AddRestart] expected 1 restart kept, but got %d", n)
+	}
+
+	cases := []struct {
+		at   time.Time
+		want int
+	}{
+		{now, 1},
+		{now.Add(4 * time.Minute), 1},
+		{now.Add(5 * time.Minute), 0},
+	}
+
+	for i, c := range cases {
+		if n := e.CountRestarts(c.at); n != c.want {
+			t.Errorf(`
+[app > domain > entity > engine > Engine.CountRestarts]
+Index:    %d
+Expected: %d
+Actual:   %d
+`, i, c.want, n)
+		}
+	}
+	if n := len(e.restartedAt); n != 0 {
+		t.Errorf("[Engine.CountRestarts] expected the restarts to be forgotten, but %d are kept", n)
+	}
+}
+
+func TestEngine_GetStatus_Restarts(t *testing.T) {
+	e := New("test", "/path/to/engine")
+	e.SetRestartWindow(10 * time.Minute)
+
+	now := time.Now()
+	e.AddRestart(now.Add(-11 * time.Minute))
+
+	// the restart out of the window is not reported, even before forgotten
+	if n := e.GetStatus().Restarts; n != 0 {
+		t.Errorf("[Engine.GetStatus] expected no restarts in the window, but got %d", n)
+	}
+
+	e.AddRestart(now.Add(-time.Minute))
+	if n := e.GetStatus().Restarts; n != 1 {
+		t.Errorf("[Engine.GetStatus] expected 1 restart in the window, but got %d", n)
+	}
+}
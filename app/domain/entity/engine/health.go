@@ -0,0 +1,46 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package engine
+
+import "time"
+
+// Health is the result of the health checks of the engine.
+// The engine is checked by 'isready' while it is not thinking.
+type Health struct {
+	// Healthy is false after the checks failed repeatedly.
+	Healthy bool `json:"healthy"`
+
+	// LatencyMs is the time from 'isready' to 'readyok' of the last
+	// successful check, in milliseconds.
+	LatencyMs float64 `json:"latencyMs"`
+
+	// Failures is the count of consecutive failed checks.
+	Failures int `json:"failures"`
+
+	// CheckedAt is the time of the last check.
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Succeeded returns new Health after a successful check.
+func (h Health) Succeeded(latency time.Duration, at time.Time) *Health {
+	return &Health{
+		Healthy:   true,
+		LatencyMs: float64(latency) / float64(time.Millisecond),
+		Failures:  0,
+		CheckedAt: at,
+	}
+}
+
+// Failed returns new Health after a failed check.
+// It becomes unhealthy when the checks failed maxFailures times in a row.
+func (h Health) Failed(maxFailures int, at time.Time) *Health {
+	failures := h.Failures + 1
+	return &Health{
+		Healthy:   h.Healthy && failures < maxFailures,
+		LatencyMs: h.LatencyMs,
+		Failures:  failures,
+		CheckedAt: at,
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealth_Failed(t *testing.T) {
+	at := time.Unix(0, 0)
+	cases := []struct {
+		in   Health
+		max  int
+		want Health
+	}{
+		{
+			Health{Healthy: true, LatencyMs: 1.5},
+			3,
+			Health{Healthy: true, LatencyMs: 1.5, Failures: 1, CheckedAt: at},
+		},
+		{
+			Health{Healthy: true, LatencyMs: 1.5, Failures: 2},
+			3,
+			Health{Healthy: false, LatencyMs: 1.5, Failures: 3, CheckedAt: at},
+		},
+		{
+			Health{Healthy: false, Failures: 3},
+			3,
+			Health{Healthy: false, Failures: 4, CheckedAt: at},
+		},
+	}
+
+	for i, c := range cases {
+		if res := c.in.Failed(c.max, at); *res != c.want {
+			t.Errorf(`
+[app > domain > entity > engine > Health.Failed]
+Index:    %d
+Expected: %+v
+Actual:   %+v
+`, i, c.want, *res)
+		}
+	}
+}
+
+func TestHealth_Succeeded(t *testing.T) {
+	at := time.Unix(0, 0)
+	in := Health{Healthy: false, LatencyMs: 1, Failures: 3}
+	want := Health{Healthy: true, LatencyMs: 2.5, Failures: 0, CheckedAt: at}
+
+	if res := in.Succeeded(2500*time.Microsecond, at); *res != want {
+		t.Errorf(`
+[app > domain > entity > engine > Health.Succeeded]
+Expected: %+v
+Actual:   %+v
+`, want, *res)
+	}
+}
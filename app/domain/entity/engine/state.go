@@ -24,6 +24,9 @@ const (
 
 	// Thinking is the state the connected shogi engine is thinking.
 	Thinking
+
+	// Crashed is the state the engine process has exited unexpectedly.
+	Crashed
 )
 
 func (s State) String() string {
@@ -36,11 +39,32 @@ func (s State) String() string {
 		return "State(StandBy)"
 	case Thinking:
 		return "State(Thinking)"
+	case Crashed:
+		return "State(Crashed)"
 	default:
 		return "State(Unknown)"
 	}
 }
 
+// MarshalText implements encoding.TextMarshaler.
+// The state is represented as a lower camel case name in JSON.
+func (s State) MarshalText() ([]byte, error) {
+	switch s {
+	case NotConnected:
+		return []byte("notConnected"), nil
+	case Connected:
+		return []byte("connected"), nil
+	case StandBy:
+		return []byte("standBy"), nil
+	case Thinking:
+		return []byte("thinking"), nil
+	case Crashed:
+		return []byte("crashed"), nil
+	default:
+		return []byte("unknown"), nil
+	}
+}
+
 func (s State) isValid() bool {
-	return NotConnected <= s && s <= Thinking
+	return NotConnected <= s && s <= Crashed
 }
@@ -0,0 +1,36 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestState_MarshalText(t *testing.T) {
+	cases := []struct {
+		in   State
+		want string
+	}{
+		{NotConnected, `"notConnected"`},
+		{Connected, `"connected"`},
+		{StandBy, `"standBy"`},
+		{Thinking, `"thinking"`},
+		{Crashed, `"crashed"`},
+		{State(100), `"unknown"`},
+	}
+
+	for i, c := range cases {
+		b, err := json.Marshal(c.in)
+		if err != nil || string(b) != c.want {
+			t.Errorf(`
+[app > domain > entity > engine > State.MarshalText]
+Index:    %d
+Expected: %s
+Actual:   %s (err=%v)
+`, i, c.want, string(b), err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package engine
+
+import "time"
+
+// Crash is the information of an unexpected exit of the engine process.
+type Crash struct {
+	// ExitCode is the exit code of the process.
+	// It is -1 if unknown, for example when killed by a signal.
+	ExitCode int `json:"exitCode"`
+
+	// Stderr is the last lines the engine wrote to stderr.
+	Stderr []string `json:"stderr"`
+
+	// At is the time the exit was detected.
+	At time.Time `json:"at"`
+}
+
+// Status is a summary of the engine.
+type Status struct {
+	ID    ID     `json:"id"`
+	Name  string `json:"name"`
+	State State  `json:"state"`
+
+	// Restarts is the count of restarts after crashes in the restart window.
+	Restarts int `json:"restarts"`
+
+	// Crash is the last crash. Nil if the engine has never crashed.
+	Crash *Crash `json:"crash,omitempty"`
+
+	// Health is the result of the health checks. Nil until connected.
+	Health *Health `json:"health,omitempty"`
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package event provides models of events that happen on shogi engines.
+package event
+
+import (
+	"time"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+// Type is a kind of Event.
+type Type string
+
+const (
+	// Info is an event that the engine outputs a thought result.
+	Info Type = "info"
+
+	// BestMove is an event that the engine outputs the best move.
+	BestMove Type = "bestmove"
+
+	// Checkmate is an event that the engine outputs the result of mate search.
+	Checkmate Type = "checkmate"
+
+	// Message is an event that the engine outputs 'info string'.
+	Message Type = "message"
+
+	// State is an event that the state of the engine has changed.
+	State Type = "state"
+
+	// Position is an event that the current position has changed.
+	Position Type = "position"
+
+	// Closed is an event that the engine was closed by the server.
+	Closed Type = "closed"
+)
+
+// Reasons of Closed.
+const (
+	// ReasonIdle is the Reason when the engine was idle too long.
+	ReasonIdle = "idle"
+
+	// ReasonRequested is the Reason when the engine was closed by request.
+	ReasonRequested = "requested"
+)
+
+// Event represents something happened on a shogi engine.
+// Only the fields related to the Type are filled.
+type Event struct {
+	Type     Type      `json:"type"`
+	EngineID engine.ID `json:"engineId"`
+	Time     time.Time `json:"time"`
+
+	// MultiPV is the index of the Info. Only for Info.
+	MultiPV int       `json:"multipv,omitempty"`
+	Info    *usi.Info `json:"info,omitempty"`
+
+	// BestMove is the best move of the search. Only for BestMove.
+	BestMove *usi.BestMove `json:"bestmove,omitempty"`
+
+	// Checkmate is the result of mate search. Only for Checkmate.
+	Checkmate *usi.Checkmate `json:"checkmate,omitempty"`
+
+	// Message is the 'info string' message. Only for Message.
+	Message *usi.Message `json:"message,omitempty"`
+
+	// State is the new state of the engine. Only for State.
+	State engine.State `json:"state,omitempty"`
+
+	// Position is the new position. Only for Position.
+	Position *shogi.Position `json:"position,omitempty"`
+
+	// Reason is why the engine was closed. Only for Closed.
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewInfo returns new Event of Info.
+func NewInfo(id engine.ID, mpv int, info *usi.Info) *Event {
+	return &Event{Type: Info, EngineID: id, Time: time.Now(), MultiPV: mpv, Info: info}
+}
+
+// NewBestMove returns new Event of BestMove.
+func NewBestMove(id engine.ID, b *usi.BestMove) *Event {
+	return &Event{Type: BestMove, EngineID: id, Time: time.Now(), BestMove: b}
+}
+
+// NewCheckmate returns new Event of Checkmate.
+func NewCheckmate(id engine.ID, c *usi.Checkmate) *Event {
+	return &Event{Type: Checkmate, EngineID: id, Time: time.Now(), Checkmate: c}
+}
+
+// NewMessage returns new Event of Message.
+func NewMessage(id engine.ID, m *usi.Message) *Event {
+	return &Event{Type: Message, EngineID: id, Time: time.Now(), Message: m}
+}
+
+// NewState returns new Event of State.
+func NewState(id engine.ID, state engine.State) *Event {
+	return &Event{Type: State, EngineID: id, Time: time.Now(), State: state}
+}
+
+// NewPosition returns new Event of Position.
+func NewPosition(id engine.ID, pos *shogi.Position) *Event {
+	return &Event{Type: Position, EngineID: id, Time: time.Now(), Position: pos}
+}
+
+// NewClosed returns new Event of Closed.
+func NewClosed(id engine.ID, reason string) *Event {
+	return &Event{Type: Closed, EngineID: id, Time: time.Now(), Reason: reason}
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package game
+
+import (
+	"time"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+// Evaluation is an evaluation of a position by the engine.
+type Evaluation struct {
+	// Score and ScoreType are the same as usi.Info.
+	Score     int           `json:"score"`
+	ScoreType usi.ScoreType `json:"scoreType"`
+
+	Depth int `json:"depth"`
+
+	// Moves is the principal variation.
+	Moves []*shogi.Move `json:"moves"`
+
+	At time.Time `json:"at"`
+}
+
+// NewEvaluation returns new Evaluation of the info. Returns nil if the info
+// has no exact score, because such infos are not evaluations.
+func NewEvaluation(info *usi.Info) *Evaluation {
+	if info.ScoreType == "" || info.LowerBound || info.UpperBound {
+		return nil
+	}
+	return &Evaluation{
+		Score:     info.Score,
+		ScoreType: info.ScoreType,
+		Depth:     info.Values["depth"],
+		Moves:     info.Moves,
+		At:        info.ReceivedAt,
+	}
+}
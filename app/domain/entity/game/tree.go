@@ -0,0 +1,263 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package game provides models of game records, which are trees of moves
+// with variations.
+package game
+
+import (
+	"errors"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+var (
+	// ErrNodeNotFound is returned when there is no node of the ID.
+	ErrNodeNotFound = errors.New("node not found")
+
+	// ErrRootNode is returned when the operation is not allowed for the root.
+	ErrRootNode = errors.New("the operation is not allowed for the root node")
+)
+
+// NodeID identifies a node in a Tree.
+type NodeID int
+
+// Node is a position in the game, reached by the move from its parent.
+type Node struct {
+	ID NodeID `json:"id"`
+
+	// Move is the move from the parent. Nil for the root.
+	Move *shogi.Move `json:"move,omitempty"`
+
+	Comment string `json:"comment,omitempty"`
+
+	// Evaluation is the last evaluation of the position by the engine.
+	Evaluation *Evaluation `json:"evaluation,omitempty"`
+
+	// Children are the next moves. The first one is the main line,
+	// and the others are variations.
+	Children []*Node `json:"children"`
+
+	// Visited is the ID of the child the current node was last selected
+	// through. 0 if none, because the root is never a child.
+	Visited NodeID `json:"visited,omitempty"`
+}
+
+// Next returns the child last visited, or the main line if none.
+// Returns nil if the node has no children.
+func (n *Node) Next() *Node {
+	for _, c := range n.Children {
+		if c.ID == n.Visited {
+			return c
+		}
+	}
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[0]
+}
+
+// Tree is a game record with variations.
+type Tree struct {
+	// Initial is the position of the root.
+	Initial *shogi.Position `json:"initial"`
+
+	Root *Node `json:"root"`
+
+	// Current is the ID of the node of the current position.
+	Current NodeID `json:"current"`
+
+	// NextID is the ID given to the next added node.
+	NextID NodeID `json:"nextId"`
+}
+
+// NewTree returns new Tree which starts from the position.
+func NewTree(initial *shogi.Position) *Tree {
+	return &Tree{
+		Initial: initial,
+		Root:    &Node{ID: 0, Children: []*Node{}},
+		Current: 0,
+		NextID:  1,
+	}
+}
+
+// Find returns the node of the ID.
+func (t *Tree) Find(id NodeID) (*Node, bool) {
+	path := t.Path(id)
+	if path == nil {
+		return nil, false
+	}
+	return path[len(path)-1], true
+}
+
+// Path returns the nodes from the root to the node of the ID.
+// Returns nil if the node is not found.
+func (t *Tree) Path(id NodeID) []*Node {
+	var walk func(n *Node, path []*Node) []*Node
+	walk = func(n *Node, path []*Node) []*Node {
+		path = append(path, n)
+		if n.ID == id {
+			return path
+		}
+		for _, c := range n.Children {
+			if p := walk(c, path); p != nil {
+				return p
+			}
+		}
+		return nil
+	}
+	return walk(t.Root, nil)
+}
+
+// Moves returns the moves from the root to the node of the ID.
+func (t *Tree) Moves(id NodeID) ([]*shogi.Move, error) {
+	path := t.Path(id)
+	if path == nil {
+		return nil, ErrNodeNotFound
+	}
+	moves := make([]*shogi.Move, 0, len(path)-1)
+	for _, n := range path[1:] {
+		moves = append(moves, n.Move)
+	}
+	return moves, nil
+}
+
+// CurrentNode returns the node of the current position.
+func (t *Tree) CurrentNode() *Node {
+	n, _ := t.Find(t.Current)
+	return n
+}
+
+// Line returns the nodes from the root to the current node, followed by
+// the nodes last visited after it. See Node.Next. The index is the ply.
+func (t *Tree) Line() []*Node {
+	line := t.Path(t.Current)
+	for n := line[len(line)-1].Next(); n != nil; n = n.Next() {
+		line = append(line, n)
+	}
+	return line
+}
+
+// Select makes the node of the ID the current node, and records the
+// path to it as visited, so that the line after its ancestors goes
+// through it.
+func (t *Tree) Select(id NodeID) error {
+	path := t.Path(id)
+	if path == nil {
+		return ErrNodeNotFound
+	}
+	for i := 1; i < len(path); i++ {
+		path[i-1].Visited = path[i].ID
+	}
+	t.Current = id
+	return nil
+}
+
+// Game returns the line of the tree as a game without variations.
+// The current node is at the ply.
+func (t *Tree) Game() *shogi.Game {
+	line := t.Line()
+	moves := make([]*shogi.Move, 0, len(line)-1)
+	for _, n := range line[1:] {
+		moves = append(moves, n.Move)
+	}
+	return &shogi.Game{
+		Initial: t.Initial,
+		Moves:   moves,
+		Ply:     len(t.Path(t.Current)) - 1,
+	}
+}
+
+// Add adds the move as the child of the node, and returns the child.
+// If the node already has the move, the existing child is returned.
+// Added moves become variations, except for the first one.
+func (t *Tree) Add(parent NodeID, move *shogi.Move) (*Node, error) {
+	p, ok := t.Find(parent)
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	for _, c := range p.Children {
+		if sameMove(c.Move, move) {
+			return c, nil
+		}
+	}
+
+	n := &Node{ID: t.NextID, Move: move, Children: []*Node{}}
+	t.NextID++
+	p.Children = append(p.Children, n)
+	return n, nil
+}
+
+// Delete deletes the node and its descendants. If the current node is
+// deleted, the parent of the deleted node becomes the current node.
+func (t *Tree) Delete(id NodeID) error {
+	if id == t.Root.ID {
+		return ErrRootNode
+	}
+	path := t.Path(id)
+	if path == nil {
+		return ErrNodeNotFound
+	}
+
+	parent := path[len(path)-2]
+	for i, c := range parent.Children {
+		if c.ID == id {
+			parent.Children = append(parent.Children[:i:i], parent.Children[i+1:]...)
+			break
+		}
+	}
+
+	if _, ok := t.Find(t.Current); !ok {
+		t.Current = parent.ID
+	}
+	return nil
+}
+
+// Promote makes the node the main line of its siblings.
+func (t *Tree) Promote(id NodeID) error {
+	if id == t.Root.ID {
+		return ErrRootNode
+	}
+	path := t.Path(id)
+	if path == nil {
+		return ErrNodeNotFound
+	}
+
+	parent := path[len(path)-2]
+	for i, c := range parent.Children {
+		if c.ID == id {
+			copy(parent.Children[1:i+1], parent.Children[:i])
+			parent.Children[0] = c
+			break
+		}
+	}
+	return nil
+}
+
+// Clone returns a deep copy of the tree. The moves and the positions
+// are shared, because they are not modified.
+func (t *Tree) Clone() *Tree {
+	var clone func(n *Node) *Node
+	clone = func(n *Node) *Node {
+		c := *n
+		c.Children = make([]*Node, len(n.Children))
+		for i, child := range n.Children {
+			c.Children[i] = clone(child)
+		}
+		return &c
+	}
+	return &Tree{
+		Initial: t.Initial,
+		Root:    clone(t.Root),
+		Current: t.Current,
+		NextID:  t.NextID,
+	}
+}
+
+func sameMove(a, b *shogi.Move) bool {
+	return *a.Source == *b.Source &&
+		*a.Dest == *b.Dest &&
+		a.PieceID == b.PieceID &&
+		a.IsPromoted == b.IsPromoted
+}
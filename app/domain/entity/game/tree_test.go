@@ -0,0 +1,200 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package game
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+func move(sr, sc, dr, dc int) *shogi.Move {
+	return &shogi.Move{Source: &shogi.Point{Row: sr, Column: sc}, Dest: &shogi.Point{Row: dr, Column: dc}}
+}
+
+// ids returns the IDs of the nodes.
+func ids(nodes []*Node) []NodeID {
+	res := make([]NodeID, len(nodes))
+	for i, n := range nodes {
+		res[i] = n.ID
+	}
+	return res
+}
+
+// newTestTree returns the tree below, where the current node is 3.
+//
+//	0 - 1 (7g7f) - 2 (3c3d) - 3 (2g2f)
+//	              \ 4 (8c8d)
+//	\ 5 (2g2f)
+func newTestTree(t *testing.T) *Tree {
+	t.Helper()
+	tree := NewTree(&shogi.Position{})
+	add := func(parent NodeID, m *shogi.Move) {
+		t.Helper()
+		if _, err := tree.Add(parent, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(0, move(6, 6, 5, 6))
+	add(1, move(2, 2, 3, 2))
+	add(2, move(6, 1, 5, 1))
+	add(1, move(2, 7, 3, 7))
+	add(0, move(6, 1, 5, 1))
+	tree.Current = 3
+	return tree
+}
+
+func TestTree_Add(t *testing.T) {
+	tree := newTestTree(t)
+
+	// the existing move is not added
+	n, err := tree.Add(0, move(6, 6, 5, 6))
+	if err != nil || n.ID != 1 || len(tree.Root.Children) != 2 || tree.NextID != 6 {
+		t.Errorf("[app > domain > entity > game > Tree.Add] the existing node is not returned. node=%v, err=%v", n, err)
+	}
+
+	n, err = tree.Add(4, move(6, 1, 5, 1))
+	if err != nil || n.ID != 6 || tree.NextID != 7 {
+		t.Errorf("[app > domain > entity > game > Tree.Add] unexpected node. node=%v, err=%v", n, err)
+	}
+
+	if _, err := tree.Add(100, move(6, 1, 5, 1)); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("[app > domain > entity > game > Tree.Add] expected ErrNodeNotFound, but got %v", err)
+	}
+}
+
+func TestTree_Line(t *testing.T) {
+	tree := newTestTree(t)
+
+	cases := []struct {
+		current NodeID
+		want    []NodeID
+	}{
+		{0, []NodeID{0, 1, 2, 3}},
+		{3, []NodeID{0, 1, 2, 3}},
+		{4, []NodeID{0, 1, 4}},
+		{5, []NodeID{0, 5}},
+	}
+
+	for i, c := range cases {
+		tree.Current = c.current
+		if res := ids(tree.Line()); !reflect.DeepEqual(res, c.want) {
+			t.Errorf(`
+[app > domain > entity > game > Tree.Line]
+Index:    %d
+Expected: %v
+Actual:   %v
+`, i, c.want, res)
+		}
+	}
+
+	tree.Current = 4
+	g := tree.Game()
+	if g.Initial != tree.Initial || len(g.Moves) != 2 || g.Ply != 2 || g.Moves[1] != tree.Line()[2].Move {
+		t.Errorf("[app > domain > entity > game > Tree.Game] unexpected game. game=%v", g)
+	}
+
+	tree.Current = 3
+	moves, err := tree.Moves(3)
+	if err != nil || len(moves) != 3 || moves[2] != tree.Line()[3].Move {
+		t.Errorf("[app > domain > entity > game > Tree.Moves] unexpected moves. moves=%v, err=%v", moves, err)
+	}
+}
+
+func TestTree_Select(t *testing.T) {
+	tree := newTestTree(t)
+
+	// the main line is followed when no child has been visited
+	tree.Current = 1
+	if res := ids(tree.Line()); !reflect.DeepEqual(res, []NodeID{0, 1, 2, 3}) {
+		t.Errorf("[app > domain > entity > game > Tree.Select] unexpected line before select: %v", res)
+	}
+
+	// the line goes through the selected node after going back
+	if err := tree.Select(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Select(0); err != nil {
+		t.Fatal(err)
+	}
+	if res := ids(tree.Line()); !reflect.DeepEqual(res, []NodeID{0, 1, 4}) || tree.Current != 0 {
+		t.Errorf("[app > domain > entity > game > Tree.Select] unexpected line after select: %v", res)
+	}
+
+	// the deleted child is not followed
+	if err := tree.Delete(4); err != nil {
+		t.Fatal(err)
+	}
+	if n := tree.Root.Children[0].Next(); n == nil || n.ID != 2 {
+		t.Errorf("[app > domain > entity > game > Node.Next] expected 2, but got %v", n)
+	}
+
+	if err := tree.Select(100); !errors.Is(err, ErrNodeNotFound) || tree.Current != 0 {
+		t.Errorf("[app > domain > entity > game > Tree.Select] expected ErrNodeNotFound, but got %v", err)
+	}
+}
+
+func TestTree_Delete(t *testing.T) {
+	cases := []struct {
+		id       NodeID
+		err      error
+		current  NodeID
+		children []NodeID
+	}{
+		{2, nil, 1, []NodeID{4}},
+		{4, nil, 3, []NodeID{2}},
+		{0, ErrRootNode, 3, []NodeID{2, 4}},
+		{100, ErrNodeNotFound, 3, []NodeID{2, 4}},
+	}
+
+	for i, c := range cases {
+		tree := newTestTree(t)
+		err := tree.Delete(c.id)
+		n, _ := tree.Find(1)
+		if !errors.Is(err, c.err) || tree.Current != c.current || !reflect.DeepEqual(ids(n.Children), c.children) {
+			t.Errorf(`
+[app > domain > entity > game > Tree.Delete]
+Index:    %d
+Expected: %v, current=%d, children=%v
+Actual:   %v, current=%d, children=%v
+`, i, c.err, c.current, c.children, err, tree.Current, ids(n.Children))
+		}
+	}
+}
+
+func TestTree_Promote(t *testing.T) {
+	tree := newTestTree(t)
+	if _, err := tree.Add(1, move(6, 1, 5, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tree.Promote(6); err != nil {
+		t.Fatal(err)
+	}
+	n, _ := tree.Find(1)
+	if want := []NodeID{6, 2, 4}; !reflect.DeepEqual(ids(n.Children), want) {
+		t.Errorf("[app > domain > entity > game > Tree.Promote] expected %v, but got %v", want, ids(n.Children))
+	}
+
+	if err := tree.Promote(0); !errors.Is(err, ErrRootNode) {
+		t.Errorf("[app > domain > entity > game > Tree.Promote] expected ErrRootNode, but got %v", err)
+	}
+}
+
+func TestTree_Clone(t *testing.T) {
+	tree := newTestTree(t)
+	clone := tree.Clone()
+
+	if err := clone.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	clone.Root.Comment = "changed"
+
+	if _, ok := tree.Find(1); !ok || tree.Root.Comment != "" || tree.Current != 3 {
+		t.Error("[app > domain > entity > game > Tree.Clone] the original tree is changed")
+	}
+}
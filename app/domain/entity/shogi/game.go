@@ -0,0 +1,20 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shogi
+
+// Game represents the history of a game,
+// which is the initial position and the moves made from it.
+type Game struct {
+	// Initial is the position the moves are made from.
+	Initial *Position `json:"initial"`
+
+	// Moves are the moves made from the initial position.
+	// The moves after Ply are kept to be redone.
+	Moves []*Move `json:"moves"`
+
+	// Ply is the count of moves made to reach the current position.
+	// It is between 0 and len(Moves).
+	Ply int `json:"ply"`
+}
@@ -1,14 +1,69 @@
 package usi
 
-import "github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+import (
+	"time"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+// ScoreType is a kind of score in info.
+type ScoreType string
+
+const (
+	// ScoreCP is a score in centipawns. e.g. 'score cp 100'
+	ScoreCP ScoreType = "cp"
+
+	// ScoreMate is a score of mate in N plies. e.g. 'score mate 5', 'score mate -5'
+	// Negative value means the engine is getting mated.
+	ScoreMate ScoreType = "mate"
+
+	// ScoreMateUnknown is a score of mate in unknown plies, only the sign is known.
+	// e.g. 'score mate +', 'score mate -'
+	ScoreMateUnknown ScoreType = "mateUnknown"
+)
 
 // Info represents the output from the shogi engine.
-// We drop the info string, for now.
+// 'info string' lines are handled as Message, not Info.
 type Info struct {
-	// depth, seldepth, time, nodes, nps, hashfull
+	// depth, seldepth, time, nodes, nps, hashfull, currmovenumber, cpuload
 	Values map[string]int `json:"values"`
 
+	// Score is the evaluation value in centipawns when ScoreType is ScoreCP,
+	// the number of plies to mate when ScoreMate, and 1 or -1 (the sign)
+	// when ScoreMateUnknown.
 	Score int `json:"score"`
 
+	// ScoreType is a kind of Score. Empty if the engine did not output score.
+	ScoreType ScoreType `json:"scoreType"`
+
+	// LowerBound and UpperBound are true when the Score is a bound,
+	// not an exact value.
+	LowerBound bool `json:"lowerbound"`
+	UpperBound bool `json:"upperbound"`
+
 	Moves []*shogi.Move `json:"moves"`
+
+	// CurrMove is the move currently searching.
+	CurrMove *shogi.Move `json:"currmove,omitempty"`
+
+	// Refutation is the moves refuting the first move.
+	Refutation []*shogi.Move `json:"refutation,omitempty"`
+
+	// CurrLine is the line the CPU currently searching.
+	// CurrLineCPU is the CPU number, 0 if omitted.
+	CurrLine    []*shogi.Move `json:"currline,omitempty"`
+	CurrLineCPU int           `json:"currlineCpu,omitempty"`
+
+	// String is the string given at the end of info, e.g. 'info depth 1 string ...'
+	String string `json:"string,omitempty"`
+
+	// Others holds unknown tokens and their values.
+	Others map[string]string `json:"others,omitempty"`
+
+	// Position is the position this info belongs to.
+	// Nil if no position has been set yet.
+	Position *PositionTag `json:"position,omitempty"`
+
+	// ReceivedAt is the time the info was received from the engine.
+	ReceivedAt time.Time `json:"receivedAt"`
 }
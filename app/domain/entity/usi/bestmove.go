@@ -0,0 +1,36 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package usi
+
+import (
+	"time"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+// BestMove represents 'bestmove' output from the shogi engine.
+//
+//	bestmove <move> [ponder <move>]
+//	bestmove resign
+//	bestmove win
+type BestMove struct {
+	// Move is the best move. Nil when the engine resigned or declared win.
+	Move *shogi.Move `json:"move,omitempty"`
+
+	// Ponder is the move the engine expects the opponent to play. Optional.
+	Ponder *shogi.Move `json:"ponder,omitempty"`
+
+	// Resign is true when the engine resigned.
+	Resign bool `json:"resign"`
+
+	// Win is true when the engine declared win (nyugyoku).
+	Win bool `json:"win"`
+
+	// Position is the position this best move belongs to.
+	Position *PositionTag `json:"position,omitempty"`
+
+	// ReceivedAt is the time the best move was received from the engine.
+	ReceivedAt time.Time `json:"receivedAt"`
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package usi
+
+import (
+	"time"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+// CheckmateStatus is a result kind of 'go mate'.
+type CheckmateStatus string
+
+const (
+	// Mate means the engine found the mate. e.g. 'checkmate 7g7f 3c3d'
+	Mate CheckmateStatus = "mate"
+
+	// NoMate means the engine proved there is no mate. 'checkmate nomate'
+	NoMate CheckmateStatus = "nomate"
+
+	// Timeout means the engine could not find the mate in time. 'checkmate timeout'
+	Timeout CheckmateStatus = "timeout"
+
+	// NotImplemented means the engine does not support mate search.
+	// 'checkmate notimplemented'
+	NotImplemented CheckmateStatus = "notimplemented"
+)
+
+// Checkmate represents 'checkmate' output from the shogi engine.
+type Checkmate struct {
+	Status CheckmateStatus `json:"status"`
+
+	// Moves is the solution. Only when the Status is Mate.
+	Moves []*shogi.Move `json:"moves,omitempty"`
+
+	// Position is the position this result belongs to.
+	Position *PositionTag `json:"position,omitempty"`
+
+	// ReceivedAt is the time the result was received from the engine.
+	ReceivedAt time.Time `json:"receivedAt"`
+}
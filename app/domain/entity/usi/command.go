@@ -4,6 +4,8 @@
 
 package usi
 
+import "fmt"
+
 var (
 	// Command is a set of USI commands.
 	Command = struct {
@@ -30,3 +32,12 @@ var (
 		ReadyOK: []byte("readyok"),
 	}
 )
+
+// MateCommand returns 'go mate' command bytes.
+// The timeout is in milliseconds, and 0 means infinite.
+func MateCommand(timeout int) []byte {
+	if timeout <= 0 {
+		return []byte("go mate infinite")
+	}
+	return []byte(fmt.Sprintf("go mate %d", timeout))
+}
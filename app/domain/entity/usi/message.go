@@ -0,0 +1,14 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package usi
+
+import "time"
+
+// Message represents 'info string' output from the shogi engine.
+// Engines use it for loading progress, book hits, warnings and so on.
+type Message struct {
+	Text       string    `json:"text"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
@@ -0,0 +1,19 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package usi
+
+import "time"
+
+// PositionTag identifies the position that a thought result belongs to.
+type PositionTag struct {
+	// SFEN of the position.
+	SFEN string `json:"sfen"`
+
+	// Revision is incremented each time a position is set to the engine.
+	Revision uint64 `json:"revision"`
+
+	// SetAt is the time the position was set.
+	SetAt time.Time `json:"setAt"`
+}
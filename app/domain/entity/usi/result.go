@@ -6,3 +6,45 @@ package usi
 
 // Result is a thought result of the shogi engine.
 type Result map[int]*Info
+
+// ChangeType is a kind of ResultChange.
+type ChangeType string
+
+const (
+	// Upsert is a change that an Info is inserted or updated.
+	Upsert ChangeType = "upsert"
+
+	// Delete is a change that all Infos are deleted.
+	Delete ChangeType = "delete"
+
+	// SetBestMove is a change that the engine output the best move.
+	SetBestMove ChangeType = "bestmove"
+
+	// SetCheckmate is a change that the engine output the result of mate search.
+	SetCheckmate ChangeType = "checkmate"
+
+	// Snapshot is not a change itself, but the whole Result at the time.
+	// It is given when the changes since the last seen one are not available.
+	Snapshot ChangeType = "snapshot"
+)
+
+// ResultChange represents a change of the Result of an engine.
+type ResultChange struct {
+	// ID is a sequential number of the change, unique in each engine.
+	ID uint64 `json:"id"`
+
+	Type ChangeType `json:"type"`
+
+	// MultiPV and Info are set on Upsert.
+	MultiPV int   `json:"multipv,omitempty"`
+	Info    *Info `json:"info,omitempty"`
+
+	// BestMove is set on SetBestMove, and on Snapshot if exists.
+	BestMove *BestMove `json:"bestmove,omitempty"`
+
+	// Checkmate is set on SetCheckmate, and on Snapshot if exists.
+	Checkmate *Checkmate `json:"checkmate,omitempty"`
+
+	// Result is set on Snapshot.
+	Result Result `json:"result,omitempty"`
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package usi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SearchLimit is a set of limits of the search given by 'go' command.
+// Zero values mean no limit. When all values are zero, the search is infinite.
+type SearchLimit struct {
+	// Depth limits the search depth in plies.
+	Depth int `json:"depth"`
+
+	// Nodes limits the number of nodes to search.
+	Nodes int `json:"nodes"`
+
+	// MoveTime is the exact time to search in milliseconds.
+	MoveTime int `json:"movetime"`
+
+	// BTime and WTime are the remaining time of each player in milliseconds.
+	BTime int `json:"btime"`
+	WTime int `json:"wtime"`
+
+	// Byoyomi is the byoyomi in milliseconds.
+	// Can not be used with BInc and WInc.
+	Byoyomi int `json:"byoyomi"`
+
+	// BInc and WInc are the increments (Fischer) in milliseconds.
+	BInc int `json:"binc"`
+	WInc int `json:"winc"`
+
+	// SearchMoves restricts the search to these moves. USI move format.
+	SearchMoves []string `json:"searchmoves"`
+}
+
+// IsInfinite returns true if there are no limits.
+func (l *SearchLimit) IsInfinite() bool {
+	return l == nil || (l.Depth == 0 &&
+		l.Nodes == 0 &&
+		l.MoveTime == 0 &&
+		l.BTime == 0 &&
+		l.WTime == 0 &&
+		l.Byoyomi == 0 &&
+		l.BInc == 0 &&
+		l.WInc == 0)
+}
+
+// Validate validates the limits. It does not validate the moves.
+func (l *SearchLimit) Validate() error {
+	if l == nil {
+		return nil
+	}
+	for _, v := range []int{l.Depth, l.Nodes, l.MoveTime, l.BTime, l.WTime, l.Byoyomi, l.BInc, l.WInc} {
+		if v < 0 {
+			return fmt.Errorf("[SearchLimit.Validate] negative value. limit=%s", l)
+		}
+	}
+	if l.Byoyomi != 0 && (l.BInc != 0 || l.WInc != 0) {
+		return errors.New("[SearchLimit.Validate] byoyomi can not be used with binc or winc")
+	}
+	return nil
+}
+
+// ToUSI returns USI go command bytes.
+func (l *SearchLimit) ToUSI() []byte {
+	var a []string
+
+	if l != nil && len(l.SearchMoves) != 0 {
+		a = append(a, "searchmoves")
+		a = append(a, l.SearchMoves...)
+	}
+
+	if l.IsInfinite() {
+		return []byte(strings.Join(append([]string{"go"}, append(a, "infinite")...), " "))
+	}
+
+	add := func(key string, v int) { a = append(a, fmt.Sprintf("%s %d", key, v)) }
+
+	if l.BTime != 0 || l.WTime != 0 || l.Byoyomi != 0 || l.BInc != 0 || l.WInc != 0 {
+		add("btime", l.BTime)
+		add("wtime", l.WTime)
+		if l.BInc != 0 || l.WInc != 0 {
+			add("binc", l.BInc)
+			add("winc", l.WInc)
+		} else {
+			add("byoyomi", l.Byoyomi)
+		}
+	}
+	if l.Depth != 0 {
+		add("depth", l.Depth)
+	}
+	if l.Nodes != 0 {
+		add("nodes", l.Nodes)
+	}
+	if l.MoveTime != 0 {
+		add("movetime", l.MoveTime)
+	}
+
+	return []byte(strings.Join(append([]string{"go"}, a...), " "))
+}
+
+func (l *SearchLimit) String() string {
+	return fmt.Sprintf(
+		"SearchLimit{depth:%d,nodes:%d,movetime:%d,btime:%d,wtime:%d,byoyomi:%d,binc:%d,winc:%d,searchmoves:%v}",
+		l.Depth, l.Nodes, l.MoveTime, l.BTime, l.WTime, l.Byoyomi, l.BInc, l.WInc, l.SearchMoves,
+	)
+}
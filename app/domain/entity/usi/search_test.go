@@ -0,0 +1,65 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package usi
+
+import "testing"
+
+func TestSearchLimit_ToUSI(t *testing.T) {
+	cases := []struct {
+		in   *SearchLimit
+		want string
+	}{
+		{nil, "go infinite"},
+		{&SearchLimit{}, "go infinite"},
+		{&SearchLimit{SearchMoves: []string{"7g7f", "2g2f"}}, "go searchmoves 7g7f 2g2f infinite"},
+		{&SearchLimit{Depth: 10}, "go depth 10"},
+		{&SearchLimit{Nodes: 100000, MoveTime: 3000}, "go nodes 100000 movetime 3000"},
+		{&SearchLimit{Byoyomi: 1000}, "go btime 0 wtime 0 byoyomi 1000"},
+		{
+			&SearchLimit{BTime: 60000, WTime: 50000, BInc: 1000, WInc: 2000},
+			"go btime 60000 wtime 50000 binc 1000 winc 2000",
+		},
+		{
+			&SearchLimit{Depth: 5, SearchMoves: []string{"7g7f"}},
+			"go searchmoves 7g7f depth 5",
+		},
+	}
+
+	for i, c := range cases {
+		res := string(c.in.ToUSI())
+		if res != c.want {
+			t.Errorf(`
+[app > domain > entity > usi > SearchLimit.ToUSI]
+Index:    %d
+Expected: %s
+Actual:   %s
+`, i, c.want, res)
+		}
+	}
+}
+
+func TestSearchLimit_Validate(t *testing.T) {
+	cases := []struct {
+		in    *SearchLimit
+		valid bool
+	}{
+		{nil, true},
+		{&SearchLimit{Depth: 10, Byoyomi: 1000}, true},
+		{&SearchLimit{Depth: -1}, false},
+		{&SearchLimit{Byoyomi: 1000, BInc: 1000}, false},
+	}
+
+	for i, c := range cases {
+		err := c.in.Validate()
+		if (err == nil) != c.valid {
+			t.Errorf(`
+[app > domain > entity > usi > SearchLimit.Validate]
+Index:    %d
+Expected: valid=%t
+Actual:   %v
+`, i, c.valid, err)
+		}
+	}
+}
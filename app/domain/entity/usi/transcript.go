@@ -0,0 +1,62 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package usi
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Direction is a direction of a line over the pipe to the engine.
+type Direction string
+
+const (
+	// Sent is a line written to the engine.
+	Sent Direction = ">"
+
+	// Received is a line the engine output.
+	Received Direction = "<"
+)
+
+// TranscriptLine is a raw line sent to or received from the engine.
+type TranscriptLine struct {
+	Direction Direction `json:"direction"`
+	Text      string    `json:"text"`
+	At        time.Time `json:"at"`
+}
+
+// String returns the line in transcript file format.
+//
+//	2020-01-02T15:04:05.123456789Z > usi
+//	2020-01-02T15:04:05.223456789Z < usiok
+func (l *TranscriptLine) String() string {
+	return l.At.Format(time.RFC3339Nano) + " " + string(l.Direction) + " " + l.Text
+}
+
+// ParseTranscriptLine parses a line in transcript file format.
+func ParseTranscriptLine(s string) (*TranscriptLine, error) {
+	a := strings.SplitN(s, " ", 3)
+	if len(a) < 2 {
+		return nil, errors.New("invalid transcript line. input = " + s)
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, a[0])
+	if err != nil {
+		return nil, errors.New("invalid time of transcript line. input = " + s)
+	}
+
+	d := Direction(a[1])
+	if d != Sent && d != Received {
+		return nil, errors.New("invalid direction of transcript line. input = " + s)
+	}
+
+	text := ""
+	if len(a) == 3 {
+		text = a[2]
+	}
+
+	return &TranscriptLine{Direction: d, Text: text, At: at}, nil
+}
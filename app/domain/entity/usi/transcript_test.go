@@ -0,0 +1,58 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package usi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTranscriptLine(t *testing.T) {
+	at := time.Date(2020, 1, 2, 15, 4, 5, 123, time.UTC)
+
+	cases := []struct {
+		in   string
+		want *TranscriptLine
+	}{
+		{
+			(&TranscriptLine{Direction: Sent, Text: "usi", At: at}).String(),
+			&TranscriptLine{Direction: Sent, Text: "usi", At: at},
+		},
+		{
+			(&TranscriptLine{Direction: Received, Text: "info depth 1 pv 7g7f", At: at}).String(),
+			&TranscriptLine{Direction: Received, Text: "info depth 1 pv 7g7f", At: at},
+		},
+		{
+			(&TranscriptLine{Direction: Received, Text: "", At: at}).String(),
+			&TranscriptLine{Direction: Received, Text: "", At: at},
+		},
+		{"2020-01-02T15:04:05Z", nil},
+		{"2020-01-02T15:04:05Z = usi", nil},
+		{"yesterday > usi", nil},
+	}
+
+	for i, c := range cases {
+		res, err := ParseTranscriptLine(c.in)
+
+		if c.want == nil {
+			if err == nil {
+				t.Errorf("[ParseTranscriptLine] expected error. Index: %d, Input: %s", i, c.in)
+			}
+			continue
+		}
+
+		if err != nil ||
+			res.Direction != c.want.Direction ||
+			res.Text != c.want.Text ||
+			!res.At.Equal(c.want.At) {
+			t.Errorf(`
+[app > domain > entity > usi > ParseTranscriptLine]
+Index:    %d
+Expected: %v
+Actual:   %v (err=%v)
+`, i, c.want, res, err)
+		}
+	}
+}
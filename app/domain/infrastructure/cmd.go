@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"time"
@@ -19,24 +20,43 @@ type Cmd interface {
 	io.Writer
 	Start() error
 	Wait(timeout time.Duration) error
+
+	// Signal sends the signal to the process, and the processes it spawned.
+	Signal(sig os.Signal) error
+
+	// Kill kills the process, and the processes it spawned.
+	Kill() error
 	Scanner() *bufio.Scanner
+	ErrScanner() *bufio.Scanner
 	Chdir(dir string)
 }
 
+// killTimeout is the max time to wait for the process to exit after SIGKILL.
+const killTimeout = 3 * time.Second
+
 type cmd struct {
 	cmd     *exec.Cmd
 	in      io.WriteCloser
-	out     io.ReadCloser
 	scanner *bufio.Scanner
+
+	errScanner *bufio.Scanner
+
+	// waitErr is the result of exec.Cmd.Wait, valid after done is closed
+	waitErr error
+	done    chan struct{}
 }
 
 // NewCmd returns new Cmd.
+// The process runs in its own process group, so that the processes it
+// spawned are terminated together.
 func NewCmd(path string) Cmd {
+	c := exec.Command(path)
+	setProcessGroup(c)
 	return &cmd{
-		cmd:     exec.Command(path),
+		cmd:     c,
 		in:      nil, // lazily initialized on Start
-		out:     nil,
 		scanner: nil,
+		done:    make(chan struct{}),
 	}
 }
 
@@ -47,31 +67,91 @@ func (c *cmd) Start() error {
 	}
 	c.in = stdin
 
-	stdout, err := c.cmd.StdoutPipe()
+	// os.Pipe instead of StdoutPipe, so that waiting for the process
+	// never closes stdout before all lines are read
+	stdout, stdoutW, err := os.Pipe()
 	if err != nil {
+		_ = stdin.Close()
 		return fmt.Errorf("get stdout pipe: %w", err)
 	}
-	c.out = stdout
+	c.cmd.Stdout = stdoutW
 	c.scanner = bufio.NewScanner(stdout)
 	c.scanner.Split(bufio.ScanLines) // just to make sure
 
-	return c.cmd.Start()
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		_ = stdin.Close()
+		_ = stdout.Close()
+		_ = stdoutW.Close()
+		return fmt.Errorf("get stderr pipe: %w", err)
+	}
+	c.cmd.Stderr = stderrW
+	c.errScanner = bufio.NewScanner(stderr)
+	c.errScanner.Split(bufio.ScanLines)
+
+	err = c.cmd.Start()
+
+	// the write ends are held by the process
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
+	if err != nil {
+		// nothing reads them when the process has not started
+		_ = stdin.Close()
+		_ = stdout.Close()
+		_ = stderr.Close()
+		return err
+	}
+
+	go func() {
+		c.waitErr = c.cmd.Wait()
+		// terminate the processes left in the group. it closes
+		// the pipes they inherited, and the reader sees EOF
+		_ = signalGroup(c.cmd.Process, os.Kill)
+		close(c.done)
+	}()
+	return nil
 }
 
+// Wait waits for the process to exit. When it does not exit within
+// the timeout, Wait kills the process group and returns an error.
 func (c *cmd) Wait(timeout time.Duration) error {
-	wait := make(chan error, 1)
-	go func() { wait <- c.cmd.Wait() }()
+	if c.cmd.Process == nil {
+		return errors.New("cmd has not started")
+	}
 
 	select {
-	case err := <-wait:
-		return err
+	case <-c.done:
+		return c.waitErr
 	case <-time.After(timeout):
-		return errors.New("timeout on closing cmd")
 	}
+
+	if err := c.Kill(); err != nil {
+		return fmt.Errorf("timeout on closing cmd. failed to kill: %w", err)
+	}
+
+	select {
+	case <-c.done:
+		return fmt.Errorf("timeout on closing cmd. killed: %w", c.waitErr)
+	case <-time.After(killTimeout):
+		return errors.New("timeout on closing cmd. the process did not exit after kill")
+	}
+}
+
+// Signal sends the signal to the process group.
+func (c *cmd) Signal(sig os.Signal) error {
+	if c.cmd.Process == nil {
+		return errors.New("cmd has not started")
+	}
+	return signalGroup(c.cmd.Process, sig)
 }
 
+// Kill kills the process group.
+func (c *cmd) Kill() error { return c.Signal(os.Kill) }
+
 func (c *cmd) Write(b []byte) (int, error) { return c.in.Write(b) }
 
 func (c *cmd) Scanner() *bufio.Scanner { return c.scanner }
 
+func (c *cmd) ErrScanner() *bufio.Scanner { return c.errScanner }
+
 func (c *cmd) Chdir(dir string) { c.cmd.Dir = filepath.Clean(dir) }
@@ -0,0 +1,104 @@
+//go:build !windows
+// +build !windows
+
+package infrastructure
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// isAlive returns true if the process is running. Zombies are not running.
+func isAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true // no procfs
+	}
+	// pid (comm) state ...
+	s := string(b)
+	fields := strings.Fields(s[strings.LastIndex(s, ")")+1:])
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+func TestCmd_Wait(t *testing.T) {
+	cases := []struct {
+		script  string
+		timeout bool
+	}{
+		// exits by itself, leaving the child
+		{"sleep 30 & echo $!", false},
+		// hangs with the child
+		{"sleep 30 & echo $!; wait", true},
+	}
+
+	for i, c := range cases {
+		sh := exec.Command("sh", "-c", c.script)
+		setProcessGroup(sh)
+		cmd := &cmd{cmd: sh, done: make(chan struct{})}
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		if !cmd.Scanner().Scan() {
+			t.Fatalf("[Cmd.Wait] Index: %d, failed to read the child pid", i)
+		}
+		child, err := strconv.Atoi(cmd.Scanner().Text())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = cmd.Wait(100 * time.Millisecond)
+		if (err != nil) != c.timeout {
+			t.Errorf(`
+[app > domain > infrastructure > Cmd.Wait]
+Index:    %d
+Expected: timeout=%v
+Actual:   %v
+`, i, c.timeout, err)
+		}
+
+		// the child is killed with the group, and stdout is closed
+		deadline := time.Now().Add(time.Second)
+		for isAlive(child) {
+			if time.Now().After(deadline) {
+				_ = syscall.Kill(child, syscall.SIGKILL)
+				t.Fatalf("[Cmd.Wait] Index: %d, the child process is alive", i)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if cmd.Scanner().Scan() {
+			t.Errorf("[Cmd.Wait] Index: %d, unexpected output: %s", i, cmd.Scanner().Text())
+		}
+	}
+}
+
+func TestCmd_Start_Error(t *testing.T) {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("no procfs")
+	}
+	before := len(fds)
+
+	for i := 0; i < 10; i++ {
+		if err := NewCmd("/not/exists").Start(); err == nil {
+			t.Fatal("[Cmd.Start] expected an error, but got nil")
+		}
+	}
+
+	// the pipes are closed when the process could not start
+	fds, err = ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fds) > before {
+		t.Errorf("[Cmd.Start] file descriptors leaked. before=%d, after=%d", before, len(fds))
+	}
+}
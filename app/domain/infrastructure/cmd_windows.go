@@ -0,0 +1,18 @@
+// Copyright 2018 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package infrastructure
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing, because process groups are not supported.
+func setProcessGroup(c *exec.Cmd) {}
+
+// signalGroup sends the signal only to p.
+func signalGroup(p *os.Process, sig os.Signal) error {
+	return p.Signal(sig)
+}
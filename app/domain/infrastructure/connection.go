@@ -1,95 +1,458 @@
 package infrastructure
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"os"
 	"sync"
+	"syscall"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
 	"github.com/murosan/shogi-board-server/app/logger"
 )
 
+// lineBufferSize is the number of lines queued for each subscriber,
+// over which info lines are dropped for the subscriber, so that a slow
+// consumer never blocks the engine's stdout. The other lines, such as
+// bestmove and readyok, are never dropped, because they are answers
+// to commands and rare.
+const lineBufferSize = 1024
+
+// stderrLines is the count of stderr lines kept for each engine.
+const stderrLines = 50
+
+// stderrWaitTimeout is the max time to wait for the engine's stderr to be
+// closed after stdout is closed, not to lose the last lines of stderr.
+const stderrWaitTimeout = time.Second
+
+// LineType is a type of a line the engine outputs.
+type LineType string
+
+const (
+	LineID        LineType = "id"
+	LineOption    LineType = "option"
+	LineUSIOK     LineType = "usiok"
+	LineReadyOK   LineType = "readyok"
+	LineInfo      LineType = "info"
+	LineBestMove  LineType = "bestmove"
+	LineCheckmate LineType = "checkmate"
+	LineOther     LineType = "other"
+)
+
+// AllLineTypes is all types of lines.
+var AllLineTypes = []LineType{
+	LineID, LineOption, LineUSIOK, LineReadyOK, LineInfo, LineBestMove, LineCheckmate, LineOther,
+}
+
+// TypeOf returns the type of the line from its first token.
+func TypeOf(b []byte) LineType {
+	token := b
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		token = b[:i]
+	}
+
+	switch t := LineType(bytes.TrimSpace(token)); t {
+	case LineID, LineOption, LineUSIOK, LineReadyOK, LineInfo, LineBestMove, LineCheckmate:
+		return t
+	default:
+		return LineOther
+	}
+}
+
 // Connector is a communicator with shogi engine service and os cmd.
 type Connector interface {
 	Connect() error
+
+	// Close waits for the engine process to exit after 'quit' is written.
+	// When it does not exit within a third of the timeout, Close sends SIGTERM,
+	// and then SIGKILL. It returns an error if the process still does not exit.
 	Close(timeout time.Duration) error
-	OnReceive(func([]byte) bool)
+
+	// Kill kills the engine process. Unlike Close, the exit is not
+	// regarded as requested, so that it is handled like a crash.
+	Kill() error
+
+	// Subscribe registers new subscriber of the lines of given types,
+	// and returns the receiving channel and a function to unsubscribe.
+	// The channel is closed on unsubscribe, or after the lines received
+	// before the engine's stdout is closed.
+	Subscribe(types ...LineType) (<-chan []byte, func())
+
+	// Exited returns a channel which is closed after the engine process has exited.
+	Exited() <-chan struct{}
+
+	// ExitStatus returns the exit code of the engine process, and whether
+	// the exit was requested by Close. It is valid after Exited is closed.
+	ExitStatus() (code int, requested bool)
+
+	// Stderr returns the last lines the engine wrote to stderr.
+	Stderr() []string
+
+	// Record sets the function called with each line of stdout in order,
+	// before the line is dispatched to subscribers. Unlike subscribers,
+	// no line is dropped for it. It must be set before Connect.
+	Record(func([]byte))
+
 	Writer() io.Writer
 }
 
 type connector struct {
 	sync.Mutex
-	cmd      Cmd
-	logger   logger.Logger
-	receiver chan []byte
-	isClosed bool
+	id          engine.ID
+	cmd         Cmd
+	logger      logger.Logger
+	subscribers map[*subscriber]struct{}
+	record      func([]byte)
+
+	// the last lines of stderr
+	stderr     []string
+	stderrDone chan struct{}
+
+	// true after the engine's stdout is closed
+	isFinished bool
+
+	// true after the process has started
+	isStarted bool
+
+	// closeMu guards the fields below, and is held while waiting
+	// for the process, so that the exit code is set only once.
+	closeMu   sync.Mutex
+	isClosed  bool
+	requested bool
+	exitCode  int
+	exited    chan struct{}
 }
 
 // NewConnector returns new Connector.
-func NewConnector(cmd Cmd, logger logger.Logger) Connector {
+func NewConnector(id engine.ID, cmd Cmd, logger logger.Logger) Connector {
 	return &connector{
-		cmd:      cmd,
-		logger:   logger,
-		receiver: make(chan []byte),
+		id:          id,
+		cmd:         cmd,
+		logger:      logger,
+		subscribers: make(map[*subscriber]struct{}),
+		stderrDone:  make(chan struct{}),
+		exited:      make(chan struct{}),
 	}
 }
 
 func (conn *connector) Connect() error {
 	conn.Lock()
 	if err := conn.cmd.Start(); err != nil {
+		conn.Unlock()
 		return err
 	}
+	conn.isStarted = true
 	conn.Unlock()
 
 	// receive on background until the pipe broken
+	go conn.receiveStderr()
 	go conn.receive()
 	return nil
 }
 
 func (conn *connector) Close(timeout time.Duration) error {
+	conn.closeMu.Lock()
+	conn.requested = true
+	conn.closeMu.Unlock()
+
 	conn.Lock()
-	defer conn.Unlock()
+	started := conn.isStarted
+	conn.Unlock()
+	if !started {
+		return nil
+	}
+
+	step := timeout / 3
+	for _, sig := range []os.Signal{nil, syscall.SIGTERM, os.Kill} {
+		if sig != nil {
+			conn.logger.Warn(
+				"[CloseEngine] engine did not exit. sending signal",
+				zap.String("engine id", conn.id.String()),
+				zap.String("signal", sig.String()),
+			)
+			if err := conn.cmd.Signal(sig); err != nil {
+				conn.logger.Warn("[CloseEngine] signal", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-conn.exited:
+			return nil
+		case <-time.After(step):
+		}
+	}
+
+	return fmt.Errorf("engine did not exit after SIGKILL. id=%s", conn.id)
+}
+
+// wait waits for the process to exit, and keeps the exit code.
+func (conn *connector) wait(timeout time.Duration) error {
+	conn.closeMu.Lock()
+	defer conn.closeMu.Unlock()
 
 	if conn.isClosed {
 		return nil
 	}
 	conn.isClosed = true
 
-	return conn.cmd.Wait(timeout)
+	err := conn.cmd.Wait(timeout)
+	conn.exitCode = exitCodeOf(err)
+	return err
 }
 
-func (conn *connector) OnReceive(block func([]byte) bool) {
-	for {
-		b, ok := <-conn.receiver
-		if !ok {
-			break // channel is closed
-		}
+func (conn *connector) Kill() error { return conn.cmd.Kill() }
 
-		if !block(b) {
-			break
-		}
+func (conn *connector) Exited() <-chan struct{} { return conn.exited }
+
+func (conn *connector) ExitStatus() (int, bool) {
+	conn.closeMu.Lock()
+	defer conn.closeMu.Unlock()
+	return conn.exitCode, conn.requested
+}
+
+func (conn *connector) Stderr() []string {
+	conn.Lock()
+	defer conn.Unlock()
+	lines := make([]string, len(conn.stderr))
+	copy(lines, conn.stderr)
+	return lines
+}
+
+func (conn *connector) Subscribe(types ...LineType) (<-chan []byte, func()) {
+	sub := newSubscriber(types)
+
+	conn.Lock()
+	if conn.isFinished {
+		// nothing will be received any more
+		sub.finish()
+	} else {
+		conn.subscribers[sub] = struct{}{}
+	}
+	conn.Unlock()
+
+	go sub.run()
+
+	unsubscribe := func() {
+		conn.Lock()
+		delete(conn.subscribers, sub)
+		conn.Unlock()
+		sub.unsubscribe()
 	}
+
+	return sub.ch, unsubscribe
+}
+
+func (conn *connector) Record(record func([]byte)) {
+	conn.Lock()
+	defer conn.Unlock()
+	conn.record = record
 }
 
 func (conn *connector) Writer() io.Writer { return conn.cmd }
 
+// dispatch queues the line to the subscribers of its type.
+func (conn *connector) dispatch(b []byte) {
+	t := TypeOf(b)
+
+	conn.Lock()
+	subs := make([]*subscriber, 0, len(conn.subscribers))
+	for sub := range conn.subscribers {
+		subs = append(subs, sub)
+	}
+	conn.Unlock()
+
+	for _, sub := range subs {
+		if !sub.types[t] {
+			continue
+		}
+		if !sub.push(t, b) {
+			conn.logger.Warn("line dropped", zap.ByteString("value", b))
+		}
+	}
+}
+
 func (conn *connector) receive() {
 	sc := conn.cmd.Scanner()
 	if sc == nil {
 		panic("scanner is nil. connect to cmd first")
 	}
 
+	conn.Lock()
+	record := conn.record
+	conn.Unlock()
+
 	for sc.Scan() {
-		conn.receiver <- sc.Bytes()
+		// the scanner reuses its buffer
+		b := make([]byte, len(sc.Bytes()))
+		copy(b, sc.Bytes())
+		if record != nil {
+			record(b)
+		}
+		conn.dispatch(b)
 	}
 
 	if err := sc.Err(); err != nil {
 		conn.logger.Warn("connection pipe broken", zap.Error(err))
 	}
 
-	close(conn.receiver)
-	if err := conn.Close(3 * time.Second); err != nil {
-		conn.logger.Warn("closing error")
+	// the pipes are closed on waiting for the process
+	select {
+	case <-conn.stderrDone:
+	case <-time.After(stderrWaitTimeout):
+	}
+
+	conn.Lock()
+	conn.isFinished = true
+	for sub := range conn.subscribers {
+		delete(conn.subscribers, sub)
+		sub.finish()
+	}
+	conn.Unlock()
+
+	if err := conn.wait(3 * time.Second); err != nil {
+		conn.logger.Warn("closing error", zap.Error(err))
+	}
+	close(conn.exited)
+}
+
+// receiveStderr logs the engine's stderr, and keeps the last lines.
+func (conn *connector) receiveStderr() {
+	defer close(conn.stderrDone)
+
+	sc := conn.cmd.ErrScanner()
+	if sc == nil {
+		return
+	}
+
+	for sc.Scan() {
+		line := sc.Text()
+		conn.logger.Warn(
+			"[EngineStderr]",
+			zap.String("engine id", conn.id.String()),
+			zap.String("value", line),
+		)
+
+		conn.Lock()
+		if len(conn.stderr) >= stderrLines {
+			conn.stderr = conn.stderr[1:]
+		}
+		conn.stderr = append(conn.stderr, line)
+		conn.Unlock()
+	}
+}
+
+// subscriber queues the lines of its types, and sends them to its channel
+// on its own goroutine, so that dispatching never blocks.
+type subscriber struct {
+	types map[LineType]bool
+	ch    chan []byte
+
+	mu       sync.Mutex
+	queue    [][]byte
+	finished bool
+
+	// wake is notified when a line is queued or finished
+	wake chan struct{}
+
+	// quit is closed on unsubscribe
+	quit chan struct{}
+	once sync.Once
+}
+
+func newSubscriber(types []LineType) *subscriber {
+	m := make(map[LineType]bool, len(types))
+	for _, t := range types {
+		m[t] = true
+	}
+	return &subscriber{
+		types: m,
+		ch:    make(chan []byte),
+		wake:  make(chan struct{}, 1),
+		quit:  make(chan struct{}),
+	}
+}
+
+// push queues the line. Returns false if the line is dropped,
+// which happens only to info lines.
+func (sub *subscriber) push(t LineType, b []byte) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if t == LineInfo && len(sub.queue) >= lineBufferSize {
+		return false
+	}
+	sub.queue = append(sub.queue, b)
+	sub.notify()
+	return true
+}
+
+// finish closes the channel after the queued lines are sent.
+func (sub *subscriber) finish() {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	sub.finished = true
+	sub.notify()
+}
+
+// unsubscribe closes the channel without sending the queued lines.
+func (sub *subscriber) unsubscribe() {
+	sub.once.Do(func() { close(sub.quit) })
+}
+
+// notify wakes run up. The lock must be held.
+func (sub *subscriber) notify() {
+	select {
+	case sub.wake <- struct{}{}:
+	default: // already notified
+	}
+}
+
+// run sends the queued lines to the channel until finished or unsubscribed.
+func (sub *subscriber) run() {
+	defer close(sub.ch)
+
+	for {
+		sub.mu.Lock()
+		if len(sub.queue) == 0 {
+			finished := sub.finished
+			sub.mu.Unlock()
+			if finished {
+				return
+			}
+			select {
+			case <-sub.wake:
+				continue
+			case <-sub.quit:
+				return
+			}
+		}
+		b := sub.queue[0]
+		sub.queue[0] = nil
+		sub.queue = sub.queue[1:]
+		sub.mu.Unlock()
+
+		select {
+		case sub.ch <- b:
+		case <-sub.quit:
+			return
+		}
+	}
+}
+
+// exitCodeOf returns the exit code from the error of waiting for the process.
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	// *exec.ExitError, or other Cmd's error which has the exit code
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
 	}
+	return -1
 }
@@ -0,0 +1,331 @@
+package infrastructure
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pipeCmd is a Cmd whose stdout and stderr are fed by the test.
+type pipeCmd struct {
+	r  *io.PipeReader
+	w  *io.PipeWriter
+	er *io.PipeReader
+	ew *io.PipeWriter
+
+	// the process exits on these signals
+	exitOn map[os.Signal]bool
+
+	mu      sync.Mutex
+	signals []os.Signal
+}
+
+func newPipeCmd() *pipeCmd {
+	r, w := io.Pipe()
+	er, ew := io.Pipe()
+	return &pipeCmd{
+		r:      r,
+		w:      w,
+		er:     er,
+		ew:     ew,
+		exitOn: map[os.Signal]bool{syscall.SIGTERM: true, os.Kill: true},
+	}
+}
+
+// exit closes stdout and stderr as if the process exited.
+func (c *pipeCmd) exit() {
+	_ = c.ew.Close()
+	_ = c.w.Close()
+}
+
+func (c *pipeCmd) Signal(sig os.Signal) error {
+	c.mu.Lock()
+	c.signals = append(c.signals, sig)
+	c.mu.Unlock()
+	if c.exitOn[sig] {
+		c.exit()
+	}
+	return nil
+}
+
+func (c *pipeCmd) Kill() error                      { return c.Signal(os.Kill) }
+func (c *pipeCmd) Write(b []byte) (int, error)      { return len(b), nil }
+func (c *pipeCmd) Start() error                     { return nil }
+func (c *pipeCmd) Wait(timeout time.Duration) error { return nil }
+func (c *pipeCmd) Scanner() *bufio.Scanner          { return bufio.NewScanner(c.r) }
+func (c *pipeCmd) ErrScanner() *bufio.Scanner       { return bufio.NewScanner(c.er) }
+func (c *pipeCmd) Chdir(dir string)                 {}
+
+func TestTypeOf(t *testing.T) {
+	cases := []struct {
+		in   string
+		want LineType
+	}{
+		{"id name engine", LineID},
+		{"option name USI_Hash type spin", LineOption},
+		{"usiok", LineUSIOK},
+		{"readyok", LineReadyOK},
+		{"info depth 1 pv 7g7f", LineInfo},
+		{"info string hello", LineInfo},
+		{"bestmove 7g7f", LineBestMove},
+		{"checkmate nomate", LineCheckmate},
+		{"information", LineOther},
+		{"", LineOther},
+	}
+
+	for i, c := range cases {
+		if res := TypeOf([]byte(c.in)); res != c.want {
+			t.Errorf(`
+[app > domain > infrastructure > TypeOf]
+Index:    %d
+Input:    %s
+Expected: %s
+Actual:   %s
+`, i, c.in, c.want, res)
+		}
+	}
+}
+
+func TestConnector_Subscribe(t *testing.T) {
+	cmd := newPipeCmd()
+	conn := NewConnector("test", cmd, zap.NewNop())
+
+	infos, unsubscribeInfos := conn.Subscribe(LineInfo)
+	defer unsubscribeInfos()
+	moves, unsubscribeMoves := conn.Subscribe(LineBestMove, LineCheckmate)
+	defer unsubscribeMoves()
+	others, unsubscribeOthers := conn.Subscribe(LineID)
+	unsubscribeOthers()
+
+	if err := conn.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		_, _ = io.WriteString(cmd.w, "info depth 1\nbestmove 7g7f\ninfo depth 2\n")
+		cmd.exit()
+	}()
+
+	assertLines(t, infos, []string{"info depth 1", "info depth 2"})
+	assertLines(t, moves, []string{"bestmove 7g7f"})
+	assertLines(t, others, []string{})
+
+	// subscribing after stdout is closed receives nothing
+	late, _ := conn.Subscribe(LineInfo)
+	assertLines(t, late, []string{})
+}
+
+func TestConnector_Subscribe_Overflow(t *testing.T) {
+	cmd := newPipeCmd()
+	conn := NewConnector("test", cmd, zap.NewNop())
+
+	lines, unsubscribe := conn.Subscribe(LineInfo, LineReadyOK, LineBestMove)
+	defer unsubscribe()
+
+	// all lines are recorded even if dropped for the subscriber
+	recorded := 0
+	conn.Record(func([]byte) { recorded++ })
+
+	if err := conn.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the subscriber reads nothing until the engine exits
+	go func() {
+		for i := 0; i < lineBufferSize*2; i++ {
+			_, _ = fmt.Fprintf(cmd.w, "info depth %d\n", i)
+		}
+		_, _ = io.WriteString(cmd.w, "readyok\nbestmove 7g7f\n")
+		cmd.exit()
+	}()
+	select {
+	case <-conn.Exited():
+	case <-time.After(time.Second):
+		t.Fatal("[Connector.Subscribe] reading stdout was blocked by the subscriber")
+	}
+
+	if recorded != lineBufferSize*2+2 {
+		t.Errorf("[Connector.Record] expected %d lines, but got %d", lineBufferSize*2+2, recorded)
+	}
+
+	// info lines are dropped, but the others are not
+	infos := 0
+	var others []string
+	for b := range lines {
+		if TypeOf(b) == LineInfo {
+			infos++
+		} else {
+			others = append(others, string(b))
+		}
+	}
+	if infos >= lineBufferSize*2 || !reflect.DeepEqual(others, []string{"readyok", "bestmove 7g7f"}) {
+		t.Errorf(`
+[app > domain > infrastructure > Connector.Subscribe]
+Expected: some infos are dropped, [readyok bestmove 7g7f]
+Actual:   %d infos, %v
+`, infos, others)
+	}
+}
+
+// assertLines receives from the channel until it is closed,
+// and compares the lines with expected ones.
+func assertLines(t *testing.T, ch <-chan []byte, want []string) {
+	t.Helper()
+
+	res := make([]string, 0)
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case b, ok := <-ch:
+			if ok {
+				res = append(res, string(b))
+				continue
+			}
+		case <-timeout:
+			t.Errorf("[Connector.Subscribe] timeout. received=%v", res)
+			return
+		}
+		break
+	}
+
+	if len(res) != len(want) {
+		t.Errorf(`
+[app > domain > infrastructure > Connector.Subscribe]
+Expected: %v
+Actual:   %v
+`, want, res)
+		return
+	}
+	for i := range want {
+		if res[i] != want[i] {
+			t.Errorf(`
+[app > domain > infrastructure > Connector.Subscribe]
+Index:    %d
+Expected: %s
+Actual:   %s
+`, i, want[i], res[i])
+		}
+	}
+}
+
+func TestConnector_ExitStatus(t *testing.T) {
+	cases := []struct {
+		close     bool
+		requested bool
+	}{
+		{false, false},
+		{true, true},
+	}
+
+	for i, c := range cases {
+		cmd := newPipeCmd()
+		conn := NewConnector("test", cmd, zap.NewNop())
+		if err := conn.Connect(); err != nil {
+			t.Fatal(err)
+		}
+
+		if c.close {
+			// exits after quit
+			go cmd.exit()
+			if err := conn.Close(time.Second); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cmd.exit()
+
+		select {
+		case <-conn.Exited():
+		case <-time.After(time.Second):
+			t.Fatalf("[Connector.Exited] timeout. Index: %d", i)
+		}
+
+		if code, requested := conn.ExitStatus(); code != 0 || requested != c.requested {
+			t.Errorf(`
+[app > domain > infrastructure > Connector.ExitStatus]
+Index:    %d
+Expected: 0, %v
+Actual:   %d, %v
+`, i, c.requested, code, requested)
+		}
+	}
+}
+
+func TestConnector_Close(t *testing.T) {
+	cases := []struct {
+		exitOn  map[os.Signal]bool
+		signals []os.Signal
+		err     bool
+	}{
+		{
+			exitOn:  map[os.Signal]bool{syscall.SIGTERM: true, os.Kill: true},
+			signals: []os.Signal{syscall.SIGTERM},
+		},
+		{
+			exitOn:  map[os.Signal]bool{os.Kill: true},
+			signals: []os.Signal{syscall.SIGTERM, os.Kill},
+		},
+		{
+			exitOn:  map[os.Signal]bool{},
+			signals: []os.Signal{syscall.SIGTERM, os.Kill},
+			err:     true,
+		},
+	}
+
+	for i, c := range cases {
+		cmd := newPipeCmd()
+		cmd.exitOn = c.exitOn
+		conn := NewConnector("test", cmd, zap.NewNop())
+		if err := conn.Connect(); err != nil {
+			t.Fatal(err)
+		}
+
+		// the process ignores quit
+		err := conn.Close(30 * time.Millisecond)
+
+		cmd.mu.Lock()
+		signals := cmd.signals
+		cmd.mu.Unlock()
+
+		if (err != nil) != c.err || !reflect.DeepEqual(signals, c.signals) {
+			t.Errorf(`
+[app > domain > infrastructure > Connector.Close]
+Index:    %d
+Expected: %v, error=%v
+Actual:   %v, error=%v
+`, i, c.signals, c.err, signals, err)
+		}
+		cmd.exit()
+	}
+}
+
+func TestConnector_Stderr(t *testing.T) {
+	cmd := newPipeCmd()
+	conn := NewConnector("test", cmd, zap.NewNop())
+	if err := conn.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < stderrLines+10; i++ {
+		_, _ = fmt.Fprintf(cmd.ew, "line %d\n", i)
+	}
+	cmd.exit()
+	<-conn.Exited()
+
+	lines := conn.Stderr()
+	want := fmt.Sprintf("line %d", stderrLines+9)
+	if len(lines) != stderrLines || lines[len(lines)-1] != want {
+		t.Errorf(`
+[app > domain > infrastructure > Connector.Stderr]
+Expected: %d lines ending with %s
+Actual:   %d lines %v
+`, stderrLines, want, len(lines), lines)
+	}
+}
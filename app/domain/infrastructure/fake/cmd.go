@@ -0,0 +1,116 @@
+package fake
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/murosan/shogi-board-server/app/domain/infrastructure"
+)
+
+var _ infrastructure.Cmd = (*Cmd)(nil)
+
+// ExitError is returned by Cmd.Wait when the engine exits with non-zero code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string { return fmt.Sprintf("exit status %d", e.Code) }
+func (e *ExitError) ExitCode() int { return e.Code }
+
+// Cmd is a infrastructure.Cmd running the fake engine in process.
+type Cmd struct {
+	*Engine
+
+	in  *io.PipeWriter
+	out *bufio.Scanner
+	err *bufio.Scanner
+
+	// closes the pipes
+	closePipes func()
+
+	once     sync.Once
+	exitCode int
+	done     chan struct{}
+}
+
+// NewCmd returns new Cmd running the script.
+func NewCmd(script *Script) *Cmd {
+	return &Cmd{
+		Engine: NewEngine(script),
+		done:   make(chan struct{}),
+	}
+}
+
+func (c *Cmd) Start() error {
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	errr, errw := io.Pipe()
+
+	c.in = inw
+	c.out = bufio.NewScanner(outr)
+	c.err = bufio.NewScanner(errr)
+
+	c.closePipes = func() {
+		// writes to stdin fail after exit, like a broken pipe
+		_ = inr.Close()
+		_ = outw.Close()
+		_ = errw.Close()
+	}
+
+	go func() { c.exit(c.Run(inr, outw, errw)) }()
+
+	return nil
+}
+
+// exit closes the pipes and finishes the process with the code.
+func (c *Cmd) exit(code int) {
+	c.once.Do(func() {
+		c.exitCode = code
+		c.closePipes()
+		close(c.done)
+	})
+}
+
+// Signal kills the engine, unless it is SIGTERM and the script ignores it.
+func (c *Cmd) Signal(sig os.Signal) error {
+	if c.closePipes == nil {
+		return errors.New("cmd has not started")
+	}
+	if sig == syscall.SIGTERM && c.script.IgnoreTerm {
+		return nil
+	}
+	c.exit(-1)
+	return nil
+}
+
+// Wait waits for the engine to exit, and kills it on timeout like the real Cmd.
+func (c *Cmd) Wait(timeout time.Duration) error {
+	select {
+	case <-c.done:
+		if c.exitCode != 0 {
+			return &ExitError{Code: c.exitCode}
+		}
+		return nil
+	case <-time.After(timeout):
+		if err := c.Kill(); err != nil {
+			return fmt.Errorf("timeout on closing cmd. failed to kill: %w", err)
+		}
+		return fmt.Errorf("timeout on closing cmd. killed: %w", &ExitError{Code: c.exitCode})
+	}
+}
+
+func (c *Cmd) Kill() error { return c.Signal(os.Kill) }
+
+func (c *Cmd) Write(b []byte) (int, error) { return c.in.Write(b) }
+
+func (c *Cmd) Scanner() *bufio.Scanner { return c.out }
+
+func (c *Cmd) ErrScanner() *bufio.Scanner { return c.err }
+
+func (c *Cmd) Chdir(dir string) {}
@@ -0,0 +1,231 @@
+// Package fake provides a scriptable fake shogi engine which speaks USI,
+// for testing without real engine binaries.
+package fake
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultInfoInterval is the interval of info lines when not specified.
+const defaultInfoInterval = 10 * time.Millisecond
+
+// Script is a behavior of the fake engine.
+type Script struct {
+	// Name and Author are output as 'id name' and 'id author'.
+	Name   string `json:"name"`
+	Author string `json:"author"`
+
+	// Options are output after 'id' lines, without the 'option ' prefix.
+	// e.g. "name USI_Hash type spin default 256 min 1 max 4096"
+	Options []string `json:"options"`
+
+	// Infos are output in order on 'go', without the 'info ' prefix.
+	Infos []string `json:"infos"`
+
+	// InfoInterval is the interval of Infos in milliseconds.
+	InfoInterval int `json:"infoInterval"`
+
+	// BestMove is output on 'stop', or after Infos when the search is finite.
+	// e.g. "7g7f ponder 3c3d"
+	BestMove string `json:"bestMove"`
+
+	// Checkmate is output on 'go mate'. e.g. "nomate", "timeout", "7g7f 3c3d"
+	Checkmate string `json:"checkmate"`
+
+	// Stderr is written to stderr on start.
+	Stderr []string `json:"stderr"`
+
+	// HangOn is a command the engine stops responding on.
+	// The engine ignores all commands after it, including 'quit'.
+	HangOn string `json:"hangOn"`
+
+	// CrashOn is a command the engine exits with CrashCode on.
+	CrashOn   string `json:"crashOn"`
+	CrashCode int    `json:"crashCode"`
+
+	// IgnoreTerm makes the engine survive SIGTERM. It is killed only by SIGKILL.
+	IgnoreTerm bool `json:"ignoreTerm"`
+}
+
+// LoadScript reads the script as JSON from the file.
+func LoadScript(path string) (*Script, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read script: %w", err)
+	}
+
+	var s Script
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("parse script: %w", err)
+	}
+	return &s, nil
+}
+
+// Engine is a fake engine running the script.
+type Engine struct {
+	script *Script
+
+	// mu guards writes to stdout and the fields below
+	mu       sync.Mutex
+	out      io.Writer
+	received []string
+	stop     chan struct{}
+	searched chan struct{}
+}
+
+// NewEngine returns new Engine.
+func NewEngine(script *Script) *Engine {
+	return &Engine{script: script}
+}
+
+// Received returns the commands the engine has received.
+func (e *Engine) Received() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	received := make([]string, len(e.received))
+	copy(received, e.received)
+	return received
+}
+
+// Run runs the engine until 'quit' or the end of stdin,
+// and returns the exit code.
+func (e *Engine) Run(stdin io.Reader, stdout, stderr io.Writer) int {
+	e.mu.Lock()
+	e.out = stdout
+	e.mu.Unlock()
+
+	for _, l := range e.script.Stderr {
+		_, _ = io.WriteString(stderr, l+"\n")
+	}
+
+	hung := false
+	sc := bufio.NewScanner(stdin)
+
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		e.mu.Lock()
+		e.received = append(e.received, line)
+		e.mu.Unlock()
+
+		if hung {
+			continue
+		}
+
+		cmd := strings.Split(line, " ")[0]
+		if cmd == e.script.HangOn {
+			hung = true
+			continue
+		}
+		if cmd == e.script.CrashOn {
+			_, _ = io.WriteString(stderr, "crashed on "+cmd+"\n")
+			e.stopSearch()
+			return e.script.CrashCode
+		}
+
+		switch cmd {
+		case "usi":
+			e.usi()
+		case "isready":
+			e.writeln("readyok")
+		case "go":
+			e.goCommand(line)
+		case "stop":
+			if e.stopSearch() {
+				e.writeln("bestmove " + e.script.BestMove)
+			}
+		case "quit":
+			e.stopSearch()
+			return 0
+		}
+	}
+
+	e.stopSearch()
+	return 0
+}
+
+func (e *Engine) usi() {
+	if e.script.Name != "" {
+		e.writeln("id name " + e.script.Name)
+	}
+	if e.script.Author != "" {
+		e.writeln("id author " + e.script.Author)
+	}
+	for _, o := range e.script.Options {
+		e.writeln("option " + o)
+	}
+	e.writeln("usiok")
+}
+
+func (e *Engine) goCommand(line string) {
+	e.stopSearch()
+
+	if strings.HasPrefix(line, "go mate") {
+		e.writeln("checkmate " + e.script.Checkmate)
+		return
+	}
+
+	interval := time.Duration(e.script.InfoInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultInfoInterval
+	}
+	infinite := strings.Contains(line, "infinite")
+
+	stop := make(chan struct{})
+	searched := make(chan struct{})
+	e.mu.Lock()
+	e.stop = stop
+	e.searched = searched
+	e.mu.Unlock()
+
+	go func() {
+		defer close(searched)
+		for _, info := range e.script.Infos {
+			select {
+			case <-stop:
+				return
+			case <-time.After(interval):
+				e.writeln("info " + info)
+			}
+		}
+		if infinite {
+			<-stop
+			return
+		}
+
+		// the search has finished by its limits
+		e.mu.Lock()
+		if e.stop == stop {
+			e.stop = nil
+		}
+		e.mu.Unlock()
+		e.writeln("bestmove " + e.script.BestMove)
+	}()
+}
+
+// stopSearch stops the current search, and returns true if it was searching.
+func (e *Engine) stopSearch() bool {
+	e.mu.Lock()
+	stop, searched := e.stop, e.searched
+	e.stop = nil
+	e.mu.Unlock()
+
+	if stop == nil {
+		return false
+	}
+	close(stop)
+	<-searched
+	return true
+}
+
+func (e *Engine) writeln(s string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = io.WriteString(e.out, s+"\n")
+}
@@ -0,0 +1,103 @@
+package infrastructure
+
+import (
+	"sync"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/event"
+)
+
+// subscriberBufferSize is the capacity of each subscriber channel.
+// The subscription is closed when its buffer is full, so that a slow
+// client never blocks the engine, and never misses events silently.
+const subscriberBufferSize = 256
+
+// Publisher is a in memory pub/sub of engine events.
+type Publisher interface {
+	// Publish sends the event to all subscribers of the engine.
+	Publish(*event.Event)
+
+	// Subscribe registers new subscriber of the engine and returns
+	// the receiving channel and a function to unsubscribe.
+	// The channel is closed on unsubscribe, on Close, or when the
+	// subscriber falls too far behind to receive all events.
+	// Then the subscriber should subscribe again to resync.
+	Subscribe(engine.ID) (<-chan *event.Event, func())
+
+	// Close closes the channels of all subscribers of the engine,
+	// so that they notice the engine has gone.
+	Close(engine.ID)
+}
+
+type publisher struct {
+	sync.RWMutex
+	subscribers map[engine.ID]map[chan *event.Event]struct{}
+}
+
+// NewPublisher returns new Publisher.
+func NewPublisher() Publisher {
+	return &publisher{
+		subscribers: make(map[engine.ID]map[chan *event.Event]struct{}),
+	}
+}
+
+func (p *publisher) Publish(e *event.Event) {
+	p.Lock()
+	defer p.Unlock()
+
+	for ch := range p.subscribers[e.EngineID] {
+		select {
+		case ch <- e:
+		default:
+			// the subscriber would miss the event
+			p.unsubscribe(e.EngineID, ch)
+		}
+	}
+}
+
+func (p *publisher) Subscribe(id engine.ID) (<-chan *event.Event, func()) {
+	ch := make(chan *event.Event, subscriberBufferSize)
+
+	p.Lock()
+	m, ok := p.subscribers[id]
+	if !ok {
+		m = make(map[chan *event.Event]struct{})
+		p.subscribers[id] = m
+	}
+	m[ch] = struct{}{}
+	p.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			p.Lock()
+			p.unsubscribe(id, ch)
+			p.Unlock()
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// unsubscribe removes the subscriber and closes its channel.
+// It does nothing if it has already been closed. The lock must be held.
+func (p *publisher) unsubscribe(id engine.ID, ch chan *event.Event) {
+	if _, ok := p.subscribers[id][ch]; !ok {
+		return
+	}
+	delete(p.subscribers[id], ch)
+	if len(p.subscribers[id]) == 0 {
+		delete(p.subscribers, id)
+	}
+	close(ch)
+}
+
+func (p *publisher) Close(id engine.ID) {
+	p.Lock()
+	defer p.Unlock()
+
+	for ch := range p.subscribers[id] {
+		close(ch)
+	}
+	delete(p.subscribers, id)
+}
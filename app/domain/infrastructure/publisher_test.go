@@ -0,0 +1,42 @@
+package infrastructure
+
+import (
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/event"
+)
+
+func TestPublisher_Publish_Overflow(t *testing.T) {
+	id := engine.ID("test")
+	p := NewPublisher()
+
+	slow, unsubscribeSlow := p.Subscribe(id)
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := p.Subscribe(id)
+	defer unsubscribeFast()
+
+	for i := 0; i < subscriberBufferSize+1; i++ {
+		p.Publish(event.NewState(id, engine.Thinking))
+		if _, ok := <-fast; !ok {
+			t.Fatalf("[Publisher.Publish] Index: %d, the fast subscriber is closed", i)
+		}
+	}
+
+	// the slow subscriber is closed after its buffer, instead of missing events
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != subscriberBufferSize {
+		t.Errorf("[Publisher.Publish] expected %d events before closing, but got %d", subscriberBufferSize, n)
+	}
+
+	// unsubscribing the closed subscriber is allowed
+	unsubscribeSlow()
+
+	p.Close(id)
+	if _, ok := <-fast; ok {
+		t.Error("[Publisher.Close] the channel is not closed")
+	}
+}
@@ -0,0 +1,146 @@
+package infrastructure
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+// replayCmd is a Cmd which replays a recorded transcript instead of
+// running the engine, so that engine-specific bugs can be reproduced in tests.
+type replayCmd struct {
+	sync.Mutex
+	lines []*usi.TranscriptLine
+	pos   int
+
+	out     *io.PipeWriter
+	scanner *bufio.Scanner
+
+	// lines to output to stdout, closed at the end of transcript
+	outputs chan string
+	// closed after stdout is closed
+	done chan struct{}
+}
+
+// NewReplayCmd reads the transcript file from r, and returns new Cmd
+// which replays it. The file format is the same as the downloaded one.
+// See domain/entity/usi/transcript.go.
+//
+// Received lines are output in order each time the expected line is written,
+// and writing an unexpected line is an error. Timestamps are ignored.
+// Stdout is closed at the end of transcript.
+func NewReplayCmd(r io.Reader) (Cmd, error) {
+	lines := make([]*usi.TranscriptLine, 0)
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		l, err := usi.ParseTranscriptLine(sc.Text())
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+
+	return &replayCmd{
+		lines:   lines,
+		outputs: make(chan string, len(lines)),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+func (c *replayCmd) Start() error {
+	r, w := io.Pipe()
+	c.out = w
+	c.scanner = bufio.NewScanner(r)
+
+	go func() {
+		for s := range c.outputs {
+			if _, err := io.WriteString(w, s+"\n"); err != nil {
+				break
+			}
+		}
+		_ = w.Close()
+		close(c.done)
+	}()
+
+	c.Lock()
+	c.flush()
+	c.Unlock()
+	return nil
+}
+
+func (c *replayCmd) Write(b []byte) (int, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	text := strings.TrimRight(string(b), "\n")
+	if c.pos >= len(c.lines) {
+		return 0, errors.New("transcript has finished. written = " + text)
+	}
+
+	expected := c.lines[c.pos]
+	if expected.Direction != usi.Sent || expected.Text != text {
+		return 0, fmt.Errorf(
+			"unexpected line was written. expected = %s, written = %s",
+			expected, text,
+		)
+	}
+	c.pos++
+	c.flush()
+
+	return len(b), nil
+}
+
+// flush outputs received lines until the next sent line.
+func (c *replayCmd) flush() {
+	for c.pos < len(c.lines) && c.lines[c.pos].Direction == usi.Received {
+		c.outputs <- c.lines[c.pos].Text
+		c.pos++
+	}
+	if c.pos == len(c.lines) {
+		close(c.outputs)
+		c.pos++ // not to close twice
+	}
+}
+
+func (c *replayCmd) Wait(timeout time.Duration) error {
+	select {
+	case <-c.done:
+		return nil
+	case <-time.After(timeout):
+		return errors.New("timeout on closing cmd")
+	}
+}
+
+// Signal stops replaying, and closes stdout as if the process was killed.
+func (c *replayCmd) Signal(sig os.Signal) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.pos <= len(c.lines) {
+		close(c.outputs)
+		c.pos = len(c.lines) + 1
+	}
+	return nil
+}
+
+func (c *replayCmd) Kill() error { return c.Signal(os.Kill) }
+
+func (c *replayCmd) Scanner() *bufio.Scanner { return c.scanner }
+
+// ErrScanner returns nil, because stderr is not recorded.
+func (c *replayCmd) ErrScanner() *bufio.Scanner { return nil }
+
+func (c *replayCmd) Chdir(dir string) {}
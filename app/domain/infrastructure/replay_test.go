@@ -0,0 +1,78 @@
+package infrastructure
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const transcript = `
+2020-01-02T15:04:05Z > usi
+2020-01-02T15:04:05.1Z < id name replay
+2020-01-02T15:04:05.2Z < usiok
+2020-01-02T15:04:06Z > isready
+2020-01-02T15:04:07Z < readyok
+2020-01-02T15:04:08Z > quit
+`
+
+func TestReplayCmd(t *testing.T) {
+	cmd, err := NewReplayCmd(strings.NewReader(transcript))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := NewConnector("test", cmd, zap.NewNop())
+	lines, unsubscribe := conn.Subscribe(AllLineTypes...)
+	defer unsubscribe()
+	if err := conn.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(s string) {
+		t.Helper()
+		if _, err := conn.Writer().Write([]byte(s + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive := func(want string) {
+		t.Helper()
+		select {
+		case b := <-lines:
+			if string(b) != want {
+				t.Errorf(`
+[app > domain > infrastructure > replayCmd]
+Expected: %s
+Actual:   %s
+`, want, string(b))
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("[replayCmd] timeout waiting for %s", want)
+		}
+	}
+
+	if _, err := conn.Writer().Write([]byte("isready\n")); err == nil {
+		t.Error("[replayCmd] expected error on unexpected line, but got nil")
+	}
+
+	write("usi")
+	receive("id name replay")
+	receive("usiok")
+	write("isready")
+	receive("readyok")
+	write("quit")
+
+	if err := conn.Close(time.Second); err != nil {
+		t.Errorf("[replayCmd] close: %v", err)
+	}
+	if _, err := conn.Writer().Write([]byte("usi\n")); err == nil {
+		t.Error("[replayCmd] expected error after the end of transcript, but got nil")
+	}
+}
+
+func TestNewReplayCmd(t *testing.T) {
+	if _, err := NewReplayCmd(strings.NewReader("usi\n")); err == nil {
+		t.Error("[NewReplayCmd] expected error on invalid transcript, but got nil")
+	}
+}
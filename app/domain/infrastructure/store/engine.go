@@ -12,6 +12,7 @@ import (
 type EngineStore interface {
 	Insert(*engine.Engine, infrastructure.Connector) error
 	Delete(engine.ID) error
+	ReplaceConnector(engine.ID, infrastructure.Connector) error
 	Find(engine.ID) (*engine.Engine, infrastructure.Connector, bool)
 	FindAllKeys() []engine.ID
 	Exists(engine.ID) bool
@@ -54,6 +55,17 @@ func (s *engineStore) Delete(id engine.ID) error {
 	return nil
 }
 
+// ReplaceConnector replaces the connector of the engine, for example on restart.
+func (s *engineStore) ReplaceConnector(id engine.ID, conn infrastructure.Connector) error {
+	if !s.Exists(id) {
+		return fmt.Errorf("no such key. id=%s", id)
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.connectors[id] = conn
+	return nil
+}
+
 func (s *engineStore) Find(id engine.ID) (*engine.Engine, infrastructure.Connector, bool) {
 	s.RLock()
 	defer s.RUnlock()
@@ -7,36 +7,93 @@ import (
 	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
 )
 
+const (
+	// changeHistorySize is the number of changes kept for each engine
+	// so that watchers can resume from the change they saw last.
+	changeHistorySize = 1024
+
+	// messageBufferSize is the number of 'info string' messages
+	// kept for each engine.
+	messageBufferSize = 100
+
+	// watcherBufferSize is the capacity of each watcher channel.
+	// The watcher is closed when its buffer is full, not to miss changes
+	// silently. It can watch again from the change it saw last.
+	watcherBufferSize = 256
+)
+
 // EngineInfoStore is a in memory store
 // for information given from shogi engines.
 type EngineInfoStore interface {
 	FindAll(engine.ID) usi.Result
+
+	// FindAllWithRevision returns the result and its revision.
+	// The revision is the ID of the last change, and increases monotonically.
+	FindAllWithRevision(engine.ID) (usi.Result, uint64)
+
 	Upsert(engine.ID, int, *usi.Info)
 	DeleteAll(engine.ID)
+
+	// FindBestMove returns the last best move of the engine.
+	// It is deleted on DeleteAll, that is when a new search starts.
+	FindBestMove(engine.ID) (*usi.BestMove, bool)
+	SetBestMove(engine.ID, *usi.BestMove)
+
+	// FindCheckmate returns the last result of mate search of the engine.
+	// It is deleted on DeleteAll, as same as the best move.
+	FindCheckmate(engine.ID) (*usi.Checkmate, bool)
+	SetCheckmate(engine.ID, *usi.Checkmate)
+
+	// AppendMessage appends the 'info string' message of the engine.
+	// Only the latest messages are kept. Messages are not deleted on DeleteAll.
+	AppendMessage(engine.ID, *usi.Message)
+	FindMessages(engine.ID) []*usi.Message
+
+	// Watch returns changes of the engine result after the given change ID,
+	// and a channel to receive following changes. If the changes after lastID
+	// are no longer kept, a Snapshot is returned instead.
+	// When lastID is 0, only a Snapshot is returned.
+	// The channel is closed on calling returned function, when the watcher
+	// falls behind, or on CloseWatchers.
+	Watch(id engine.ID, lastID uint64) ([]*usi.ResultChange, <-chan *usi.ResultChange, func())
+
+	// CloseWatchers closes the channels of all watchers of the engine.
+	CloseWatchers(engine.ID)
 }
 
 func NewEngineInfoStore() EngineInfoStore {
 	return &engineInfoStore{
-		m: make(map[engine.ID]usi.Result),
+		m:        make(map[engine.ID]usi.Result),
+		best:     make(map[engine.ID]*usi.BestMove),
+		mate:     make(map[engine.ID]*usi.Checkmate),
+		messages: make(map[engine.ID][]*usi.Message),
+		seq:      make(map[engine.ID]uint64),
+		history:  make(map[engine.ID][]*usi.ResultChange),
+		watchers: make(map[engine.ID]map[chan *usi.ResultChange]struct{}),
 	}
 }
 
 type engineInfoStore struct {
 	sync.RWMutex
-	m map[engine.ID]usi.Result
+	m        map[engine.ID]usi.Result
+	best     map[engine.ID]*usi.BestMove
+	mate     map[engine.ID]*usi.Checkmate
+	messages map[engine.ID][]*usi.Message
+	seq      map[engine.ID]uint64
+	history  map[engine.ID][]*usi.ResultChange
+	watchers map[engine.ID]map[chan *usi.ResultChange]struct{}
 }
 
 func (repo *engineInfoStore) FindAll(id engine.ID) usi.Result {
 	repo.RLock()
-	a := repo.m[id]
-	repo.RUnlock()
-
-	m := make(usi.Result)
-	for i, info := range a {
-		m[i] = info
-	}
+	defer repo.RUnlock()
+	return repo.copy(id)
+}
 
-	return m
+func (repo *engineInfoStore) FindAllWithRevision(id engine.ID) (usi.Result, uint64) {
+	repo.RLock()
+	defer repo.RUnlock()
+	return repo.copy(id), repo.seq[id]
 }
 
 func (repo *engineInfoStore) Upsert(id engine.ID, index int, info *usi.Info) {
@@ -49,10 +106,174 @@ func (repo *engineInfoStore) Upsert(id engine.ID, index int, info *usi.Info) {
 		repo.m[id] = m
 	}
 	m[index] = info
+
+	repo.notify(id, &usi.ResultChange{Type: usi.Upsert, MultiPV: index, Info: info})
 }
 
 func (repo *engineInfoStore) DeleteAll(id engine.ID) {
 	repo.Lock()
+	defer repo.Unlock()
+
+	_, ok1 := repo.m[id]
+	_, ok2 := repo.best[id]
+	_, ok3 := repo.mate[id]
+	if !ok1 && !ok2 && !ok3 {
+		return // nothing changes
+	}
 	delete(repo.m, id)
-	repo.Unlock()
+	delete(repo.best, id)
+	delete(repo.mate, id)
+
+	repo.notify(id, &usi.ResultChange{Type: usi.Delete})
+}
+
+func (repo *engineInfoStore) FindBestMove(id engine.ID) (*usi.BestMove, bool) {
+	repo.RLock()
+	defer repo.RUnlock()
+	b, ok := repo.best[id]
+	return b, ok
+}
+
+func (repo *engineInfoStore) SetBestMove(id engine.ID, b *usi.BestMove) {
+	repo.Lock()
+	defer repo.Unlock()
+
+	repo.best[id] = b
+	repo.notify(id, &usi.ResultChange{Type: usi.SetBestMove, BestMove: b})
+}
+
+func (repo *engineInfoStore) FindCheckmate(id engine.ID) (*usi.Checkmate, bool) {
+	repo.RLock()
+	defer repo.RUnlock()
+	c, ok := repo.mate[id]
+	return c, ok
+}
+
+func (repo *engineInfoStore) SetCheckmate(id engine.ID, c *usi.Checkmate) {
+	repo.Lock()
+	defer repo.Unlock()
+
+	repo.mate[id] = c
+	repo.notify(id, &usi.ResultChange{Type: usi.SetCheckmate, Checkmate: c})
+}
+
+func (repo *engineInfoStore) AppendMessage(id engine.ID, m *usi.Message) {
+	repo.Lock()
+	defer repo.Unlock()
+
+	messages := append(repo.messages[id], m)
+	if len(messages) > messageBufferSize {
+		messages = messages[len(messages)-messageBufferSize:]
+	}
+	repo.messages[id] = messages
+}
+
+func (repo *engineInfoStore) FindMessages(id engine.ID) []*usi.Message {
+	repo.RLock()
+	defer repo.RUnlock()
+
+	messages := make([]*usi.Message, len(repo.messages[id]))
+	copy(messages, repo.messages[id])
+	return messages
+}
+
+func (repo *engineInfoStore) Watch(
+	id engine.ID,
+	lastID uint64,
+) ([]*usi.ResultChange, <-chan *usi.ResultChange, func()) {
+	repo.Lock()
+	defer repo.Unlock()
+
+	var changes []*usi.ResultChange
+	history := repo.history[id]
+	if lastID != 0 &&
+		lastID <= repo.seq[id] &&
+		(len(history) == 0 || history[0].ID <= lastID+1) {
+		for _, c := range history {
+			if c.ID > lastID {
+				changes = append(changes, c)
+			}
+		}
+	} else {
+		changes = []*usi.ResultChange{{
+			ID:        repo.seq[id],
+			Type:      usi.Snapshot,
+			BestMove:  repo.best[id],
+			Checkmate: repo.mate[id],
+			Result:    repo.copy(id),
+		}}
+	}
+
+	ch := make(chan *usi.ResultChange, watcherBufferSize)
+	w, ok := repo.watchers[id]
+	if !ok {
+		w = make(map[chan *usi.ResultChange]struct{})
+		repo.watchers[id] = w
+	}
+	w[ch] = struct{}{}
+
+	var once sync.Once
+	unwatch := func() {
+		once.Do(func() {
+			repo.Lock()
+			defer repo.Unlock()
+			repo.unwatch(id, ch)
+		})
+	}
+
+	return changes, ch, unwatch
+}
+
+func (repo *engineInfoStore) CloseWatchers(id engine.ID) {
+	repo.Lock()
+	defer repo.Unlock()
+
+	for ch := range repo.watchers[id] {
+		close(ch)
+	}
+	delete(repo.watchers, id)
+}
+
+// unwatch removes the watcher and closes its channel, unless it has already
+// been closed. The write lock must be held.
+func (repo *engineInfoStore) unwatch(id engine.ID, ch chan *usi.ResultChange) {
+	if _, ok := repo.watchers[id][ch]; !ok {
+		return
+	}
+	delete(repo.watchers[id], ch)
+	if len(repo.watchers[id]) == 0 {
+		delete(repo.watchers, id)
+	}
+	close(ch)
+}
+
+// copy returns a shallow copy of the result. The lock must be held.
+func (repo *engineInfoStore) copy(id engine.ID) usi.Result {
+	m := make(usi.Result)
+	for i, info := range repo.m[id] {
+		m[i] = info
+	}
+	return m
+}
+
+// notify numbers the change, records it and sends it to watchers.
+// The write lock must be held.
+func (repo *engineInfoStore) notify(id engine.ID, change *usi.ResultChange) {
+	repo.seq[id]++
+	change.ID = repo.seq[id]
+
+	history := append(repo.history[id], change)
+	if len(history) > changeHistorySize {
+		history = history[len(history)-changeHistorySize:]
+	}
+	repo.history[id] = history
+
+	for ch := range repo.watchers[id] {
+		select {
+		case ch <- change:
+		default:
+			// the watcher would miss the change
+			repo.unwatch(id, ch)
+		}
+	}
 }
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+func TestEngineInfoStore_Watch(t *testing.T) {
+	id := engine.ID("test")
+	s := NewEngineInfoStore()
+
+	s.Upsert(id, 1, &usi.Info{})
+	s.Upsert(id, 2, &usi.Info{})
+	s.DeleteAll(id)
+	s.DeleteAll(id) // no change
+
+	cases := []struct {
+		lastID uint64
+		types  []usi.ChangeType
+		ids    []uint64
+	}{
+		{0, []usi.ChangeType{usi.Snapshot}, []uint64{3}},
+		{1, []usi.ChangeType{usi.Upsert, usi.Delete}, []uint64{2, 3}},
+		{3, []usi.ChangeType{}, []uint64{}},
+		{100, []usi.ChangeType{usi.Snapshot}, []uint64{3}},
+	}
+
+	for i, c := range cases {
+		changes, _, unwatch := s.Watch(id, c.lastID)
+		unwatch()
+
+		if len(changes) != len(c.types) {
+			t.Errorf(`
+[app > domain > infrastructure > store > EngineInfoStore.Watch]
+Index:    %d
+Expected: %v
+Actual:   %v
+`, i, c.types, changes)
+			continue
+		}
+		for j, change := range changes {
+			if change.Type != c.types[j] || change.ID != c.ids[j] {
+				t.Errorf(`
+[app > domain > infrastructure > store > EngineInfoStore.Watch]
+Index:    %d
+Expected: %s(%d)
+Actual:   %s(%d)
+`, i, c.types[j], c.ids[j], change.Type, change.ID)
+			}
+		}
+	}
+
+	_, ch, unwatch := s.Watch(id, 3)
+	s.Upsert(id, 1, &usi.Info{})
+	if c := <-ch; c.Type != usi.Upsert || c.ID != 4 {
+		t.Errorf("[EngineInfoStore.Watch] unexpected change. got=%v", c)
+	}
+	unwatch()
+	if _, ok := <-ch; ok {
+		t.Error("[EngineInfoStore.Watch] channel should be closed")
+	}
+}
+
+func TestEngineInfoStore_Watch_Close(t *testing.T) {
+	id := engine.ID("test")
+	s := NewEngineInfoStore()
+
+	// the watcher which falls behind is closed, after the buffered changes
+	_, slow, unwatchSlow := s.Watch(id, 0)
+	defer unwatchSlow()
+	for i := 0; i <= watcherBufferSize; i++ {
+		s.Upsert(id, 1, &usi.Info{})
+	}
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != watcherBufferSize {
+		t.Errorf("[EngineInfoStore.Watch] expected %d changes before closed, but got %d", watcherBufferSize, n)
+	}
+
+	// the others are closed on CloseWatchers
+	_, ch, unwatch := s.Watch(id, 0)
+	s.CloseWatchers(id)
+	if _, ok := <-ch; ok {
+		t.Error("[EngineInfoStore.CloseWatchers] channel should be closed")
+	}
+	unwatch() // does not close twice
+
+	// watching again after closed
+	_, ch, unwatch = s.Watch(id, 0)
+	defer unwatch()
+	s.DeleteAll(id)
+	if c, ok := <-ch; !ok || c.Type != usi.Delete {
+		t.Errorf("[EngineInfoStore.Watch] unexpected change after CloseWatchers. got=%v", c)
+	}
+}
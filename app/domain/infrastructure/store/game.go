@@ -2,27 +2,63 @@ package store
 
 import (
 	"sync"
+	"time"
 
 	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
 	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
 )
 
 // GameStore is a in memory store that holds game state.
 type GameStore interface {
 	FindPosition(engine.ID) (*shogi.Position, bool)
-	UpsertPosition(engine.ID, *shogi.Position)
+	FindPositionTag(engine.ID) (*usi.PositionTag, bool)
+
+	// UpsertPosition stores the position as the initial position of
+	// new game, and returns new PositionTag with incremented revision.
+	UpsertPosition(id engine.ID, pos *shogi.Position, sfen string) *usi.PositionTag
+
+	// FindGame returns a copy of the game tree of the current position.
+	FindGame(engine.ID) (*game.Tree, bool)
+
+	// UpdateGame stores the game tree, and the position of its current node.
+	// Returns new PositionTag with incremented revision.
+	UpdateGame(id engine.ID, tree *game.Tree, pos *shogi.Position, sfen string) *usi.PositionTag
+
+	// UpdateTree updates the stored game tree under the lock without changing
+	// its current node, so that evaluations set meanwhile are not lost.
+	// Returns a copy of the updated tree, or false if there is no game.
+	// The tree is not changed when update returns an error.
+	UpdateTree(id engine.ID, update func(*game.Tree) error) (*game.Tree, bool, error)
+
+	// SetEvaluation sets the evaluation to the current node, if the revision
+	// is of the current position. Returns true if it is set.
+	SetEvaluation(id engine.ID, revision uint64, eval *game.Evaluation) bool
+
 	DeletePosition(engine.ID)
 }
 
 func NewGameStore() GameStore {
 	return &gameStore{
-		pos: make(map[engine.ID]*shogi.Position),
+		pos:   make(map[engine.ID]*shogi.Position),
+		tags:  make(map[engine.ID]*usi.PositionTag),
+		games: make(map[engine.ID]*game.Tree),
+		rev:   make(map[engine.ID]uint64),
 	}
 }
 
 type gameStore struct {
 	sync.RWMutex
-	pos map[engine.ID]*shogi.Position
+	pos  map[engine.ID]*shogi.Position
+	tags map[engine.ID]*usi.PositionTag
+
+	// games hold the game trees whose current nodes are the positions.
+	games map[engine.ID]*game.Tree
+
+	// revisions are kept after deleting position,
+	// so that it increases monotonically.
+	rev map[engine.ID]uint64
 }
 
 func (s *gameStore) FindPosition(id engine.ID) (*shogi.Position, bool) {
@@ -32,14 +68,96 @@ func (s *gameStore) FindPosition(id engine.ID) (*shogi.Position, bool) {
 	return pos, ok
 }
 
-func (s *gameStore) UpsertPosition(id engine.ID, pos *shogi.Position) {
+func (s *gameStore) FindPositionTag(id engine.ID) (*usi.PositionTag, bool) {
+	s.RLock()
+	tag, ok := s.tags[id]
+	s.RUnlock()
+	return tag, ok
+}
+
+func (s *gameStore) UpsertPosition(
+	id engine.ID,
+	pos *shogi.Position,
+	sfen string,
+) *usi.PositionTag {
 	s.Lock()
+	defer s.Unlock()
+
+	s.games[id] = game.NewTree(pos)
+	return s.put(id, pos, sfen)
+}
+
+func (s *gameStore) FindGame(id engine.ID) (*game.Tree, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	tree, ok := s.games[id]
+	if !ok {
+		return nil, false
+	}
+	return tree.Clone(), true
+}
+
+func (s *gameStore) UpdateGame(
+	id engine.ID,
+	tree *game.Tree,
+	pos *shogi.Position,
+	sfen string,
+) *usi.PositionTag {
+	s.Lock()
+	defer s.Unlock()
+
+	s.games[id] = tree
+	return s.put(id, pos, sfen)
+}
+
+func (s *gameStore) UpdateTree(
+	id engine.ID,
+	update func(*game.Tree) error,
+) (*game.Tree, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	tree, ok := s.games[id]
+	if !ok {
+		return nil, false, nil
+	}
+
+	// update a copy, not to leave the tree half updated on error
+	updated := tree.Clone()
+	if err := update(updated); err != nil {
+		return nil, true, err
+	}
+	updated.Current = tree.Current
+	s.games[id] = updated
+	return updated.Clone(), true, nil
+}
+
+func (s *gameStore) SetEvaluation(id engine.ID, revision uint64, eval *game.Evaluation) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	tree, ok := s.games[id]
+	if !ok || s.rev[id] != revision {
+		return false
+	}
+	tree.CurrentNode().Evaluation = eval
+	return true
+}
+
+// put stores the position with new tag. The lock must be held.
+func (s *gameStore) put(id engine.ID, pos *shogi.Position, sfen string) *usi.PositionTag {
+	s.rev[id]++
+	tag := &usi.PositionTag{SFEN: sfen, Revision: s.rev[id], SetAt: time.Now()}
 	s.pos[id] = pos
-	s.Unlock()
+	s.tags[id] = tag
+	return tag
 }
 
 func (s *gameStore) DeletePosition(id engine.ID) {
 	s.Lock()
 	delete(s.pos, id)
+	delete(s.tags, id)
+	delete(s.games, id)
 	s.Unlock()
 }
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+func TestGameStore_UpdateGame(t *testing.T) {
+	id := engine.ID("test")
+	s := NewGameStore()
+
+	if _, ok := s.FindGame(id); ok {
+		t.Error("[GameStore.FindGame] expected not found before setting position")
+	}
+
+	initial := &shogi.Position{MoveCount: 1}
+	s.UpsertPosition(id, initial, "initial")
+
+	tree, ok := s.FindGame(id)
+	if !ok || tree.Initial != initial || len(tree.Root.Children) != 0 || tree.Current != 0 {
+		t.Errorf("[GameStore.UpsertPosition] unexpected game. game=%v", tree)
+	}
+
+	move := &shogi.Move{Source: &shogi.Point{Row: 6, Column: 6}, Dest: &shogi.Point{Row: 5, Column: 6}}
+	n, _ := tree.Add(tree.Current, move)
+	tree.Current = n.ID
+	tag := s.UpdateGame(id, tree, &shogi.Position{MoveCount: 2}, "second")
+
+	if tag.SFEN != "second" || tag.Revision != 2 {
+		t.Errorf("[GameStore.UpdateGame] unexpected tag. tag=%v", tag)
+	}
+	if pos, _ := s.FindPosition(id); pos.MoveCount != 2 {
+		t.Errorf("[GameStore.UpdateGame] the position is not stored. pos=%v", pos)
+	}
+
+	// the found game is a copy
+	found, _ := s.FindGame(id)
+	found.Root.Children = nil
+	if g, _ := s.FindGame(id); len(g.Root.Children) != 1 || g.Current != n.ID {
+		t.Errorf(`
+[app > domain > infrastructure > store > GameStore.FindGame]
+Expected: 1 child, current=%d
+Actual:   %d children, current=%d
+`, n.ID, len(g.Root.Children), g.Current)
+	}
+
+	// the evaluation of the old position is ignored
+	eval := &game.Evaluation{Score: 100, ScoreType: "cp"}
+	if s.SetEvaluation(id, 1, eval) {
+		t.Error("[GameStore.SetEvaluation] the evaluation of the old revision is set")
+	}
+	if !s.SetEvaluation(id, 2, eval) {
+		t.Error("[GameStore.SetEvaluation] the evaluation is not set")
+	}
+	if g, _ := s.FindGame(id); g.CurrentNode().Evaluation != eval {
+		t.Errorf("[GameStore.SetEvaluation] expected %v, but got %v", eval, g.CurrentNode().Evaluation)
+	}
+
+	// setting a position starts new game
+	s.UpsertPosition(id, initial, "initial")
+	if g, _ := s.FindGame(id); len(g.Root.Children) != 0 || g.Current != 0 {
+		t.Errorf("[GameStore.UpsertPosition] the game is not cleared. game=%v", g)
+	}
+
+	s.DeletePosition(id)
+	if _, ok := s.FindGame(id); ok {
+		t.Error("[GameStore.DeletePosition] the game is not deleted")
+	}
+}
+
+func TestGameStore_UpdateTree(t *testing.T) {
+	id := engine.ID("test")
+	s := NewGameStore()
+
+	if _, ok, _ := s.UpdateTree(id, func(*game.Tree) error { return nil }); ok {
+		t.Error("[GameStore.UpdateTree] expected not found before setting position")
+	}
+
+	s.UpsertPosition(id, &shogi.Position{MoveCount: 1}, "initial")
+	eval := &game.Evaluation{Score: 100, ScoreType: "cp"}
+	s.SetEvaluation(id, 1, eval)
+
+	// the evaluation is kept, and the current node is not changed
+	tree, ok, err := s.UpdateTree(id, func(tree *game.Tree) error {
+		tree.Root.Comment = "a"
+		tree.Current = 100
+		return nil
+	})
+	if !ok || err != nil || tree.Root.Comment != "a" || tree.Current != 0 {
+		t.Errorf("[GameStore.UpdateTree] unexpected result. tree=%v, ok=%v, err=%v", tree, ok, err)
+	}
+	if g, _ := s.FindGame(id); g.Root.Comment != "a" || g.CurrentNode().Evaluation != eval {
+		t.Errorf("[GameStore.UpdateTree] unexpected game. comment=%s, evaluation=%v", g.Root.Comment, g.CurrentNode().Evaluation)
+	}
+
+	// the returned tree is a copy
+	tree.Root.Comment = "b"
+	if g, _ := s.FindGame(id); g.Root.Comment != "a" {
+		t.Errorf("[GameStore.UpdateTree] the stored tree is changed via the returned tree. comment=%s", g.Root.Comment)
+	}
+
+	// the tree is not changed on error
+	want := errors.New("error")
+	if _, _, err := s.UpdateTree(id, func(tree *game.Tree) error {
+		tree.Root.Comment = "c"
+		return want
+	}); err != want {
+		t.Errorf("[GameStore.UpdateTree] expected %v, but got %v", want, err)
+	}
+	if g, _ := s.FindGame(id); g.Root.Comment != "a" {
+		t.Errorf("[GameStore.UpdateTree] the tree is changed on error. comment=%s", g.Root.Comment)
+	}
+}
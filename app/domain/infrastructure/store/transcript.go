@@ -0,0 +1,50 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+// transcriptBufferSize is the number of transcript lines kept for each engine.
+const transcriptBufferSize = 5000
+
+// TranscriptStore is a in memory store that holds raw lines
+// sent to and received from the engines.
+// Only the latest lines are kept for each engine.
+type TranscriptStore interface {
+	Append(engine.ID, *usi.TranscriptLine)
+	FindAll(engine.ID) []*usi.TranscriptLine
+}
+
+func NewTranscriptStore() TranscriptStore {
+	return &transcriptStore{
+		lines: make(map[engine.ID][]*usi.TranscriptLine),
+	}
+}
+
+type transcriptStore struct {
+	sync.RWMutex
+	lines map[engine.ID][]*usi.TranscriptLine
+}
+
+func (s *transcriptStore) Append(id engine.ID, l *usi.TranscriptLine) {
+	s.Lock()
+	defer s.Unlock()
+
+	lines := append(s.lines[id], l)
+	if len(lines) > transcriptBufferSize {
+		lines = lines[len(lines)-transcriptBufferSize:]
+	}
+	s.lines[id] = lines
+}
+
+func (s *transcriptStore) FindAll(id engine.ID) []*usi.TranscriptLine {
+	s.RLock()
+	defer s.RUnlock()
+
+	lines := make([]*usi.TranscriptLine, len(s.lines[id]))
+	copy(lines, s.lines[id])
+	return lines
+}
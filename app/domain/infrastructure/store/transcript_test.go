@@ -0,0 +1,33 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+func TestTranscriptStore(t *testing.T) {
+	id := engine.ID("test")
+	s := NewTranscriptStore()
+
+	for i := 0; i < transcriptBufferSize+10; i++ {
+		s.Append(id, &usi.TranscriptLine{Direction: usi.Sent, Text: fmt.Sprint(i)})
+	}
+
+	lines := s.FindAll(id)
+	if len(lines) != transcriptBufferSize ||
+		lines[0].Text != "10" ||
+		lines[len(lines)-1].Text != fmt.Sprint(transcriptBufferSize+9) {
+		t.Errorf(`
+[app > domain > infrastructure > store > TranscriptStore]
+Expected: %d lines from 10 to %d
+Actual:   %d lines from %s to %s
+`, transcriptBufferSize, transcriptBufferSize+9, len(lines), lines[0].Text, lines[len(lines)-1].Text)
+	}
+
+	if lines := s.FindAll("other"); len(lines) != 0 {
+		t.Errorf("[TranscriptStore] expected empty, but got %v", lines)
+	}
+}
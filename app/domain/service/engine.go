@@ -1,10 +1,19 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/murosan/shogi-board-server/app/domain/config"
 	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/event"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
 	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
 	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
 	"github.com/murosan/shogi-board-server/app/domain/framework"
@@ -13,15 +22,27 @@ import (
 	"github.com/murosan/shogi-board-server/app/logger"
 )
 
+// stopWaitTimeout is the max time to wait for the engine
+// to answer after 'stop'.
+const stopWaitTimeout = 5 * time.Second
+
+// idleCheckInterval is the max interval of checking whether the engine
+// is idle. This is a variable to be shortened in tests.
+var idleCheckInterval = 10 * time.Second
+
 // EngineService is a service for engine.
 // This service controls engine store, and delegates
 // actual engine controlling task to EngineControlService.
 type EngineService interface {
 	Connect(engine.ID) error
 	Close(engine.ID) error
-	CloseAll() error
-	Start(engine.ID) error
+	CloseAll(timeout time.Duration) error
+	Start(engine.ID, *usi.SearchLimit) error
 	Stop(engine.ID) error
+	Mate(id engine.ID, timeout int, wait time.Duration) (*usi.Checkmate, error)
+	StopAndWait(id engine.ID, timeout time.Duration) (*usi.BestMove, error)
+	GetStatus(engine.ID) (*engine.Status, error)
+	GetHealth(engine.ID) (*engine.Health, error)
 	GetOptions(engine.ID) (*engine.Options, error)
 	UpdateButtonOption(engine.ID, *engine.Button) error
 	UpdateCheckOption(engine.ID, *engine.Check) error
@@ -30,7 +51,23 @@ type EngineService interface {
 	UpdateTextOption(engine.ID, *engine.Text) error
 	GetCurrentPosition(engine.ID) (*shogi.Position, bool)
 	UpdatePosition(engine.ID, *shogi.Position) error
+	GetGame(engine.ID) (*game.Tree, bool)
+	SetGame(id engine.ID, initial *shogi.Position, moves []*shogi.Move) (*shogi.Position, error)
+	ApplyMove(engine.ID, *shogi.Move) (*shogi.Position, error)
+	Undo(engine.ID) (*shogi.Position, error)
+	Redo(engine.ID) (*shogi.Position, error)
+	Jump(id engine.ID, ply int) (*shogi.Position, error)
+	SelectNode(engine.ID, game.NodeID) (*shogi.Position, error)
+	DeleteNode(engine.ID, game.NodeID) (*game.Tree, error)
+	PromoteNode(engine.ID, game.NodeID) (*game.Tree, error)
+	CommentNode(id engine.ID, node game.NodeID, comment string) (*game.Tree, error)
 	GetResult(engine.ID) usi.Result
+	GetBestMove(engine.ID) (*usi.BestMove, bool)
+	GetMessages(engine.ID) []*usi.Message
+	GetTranscript(engine.ID) []*usi.TranscriptLine
+	WaitResult(ctx context.Context, id engine.ID, match func(revision uint64) bool, timeout time.Duration) (usi.Result, uint64)
+	Subscribe(engine.ID) (<-chan *event.Event, func(), error)
+	WatchResult(id engine.ID, lastID uint64) ([]*usi.ResultChange, <-chan *usi.ResultChange, func(), error)
 }
 
 // NewEngineService returns new EngineService.
@@ -38,19 +75,24 @@ func NewEngineService(
 	engineStore store.EngineStore,
 	engineInfoStore store.EngineInfoStore,
 	gameStore store.GameStore,
+	transcriptStore store.TranscriptStore,
+	publisher infrastructure.Publisher,
 	config *config.Config,
 	logger logger.Logger,
 	newCmd func(string) infrastructure.Cmd,
-	newConnector func(infrastructure.Cmd, logger.Logger) infrastructure.Connector,
+	newConnector func(engine.ID, infrastructure.Cmd, logger.Logger) infrastructure.Connector,
 ) EngineService {
 	return &engineService{
 		engineStore:     engineStore,
 		engineInfoStore: engineInfoStore,
 		gameStore:       gameStore,
+		transcriptStore: transcriptStore,
+		publisher:       publisher,
 		config:          config,
 		logger:          logger,
 		newCmd:          newCmd,
 		newConnector:    newConnector,
+		actors:          make(map[engine.ID]*engineActor),
 	}
 }
 
@@ -58,12 +100,20 @@ type engineService struct {
 	engineStore     store.EngineStore
 	engineInfoStore store.EngineInfoStore
 	gameStore       store.GameStore
+	transcriptStore store.TranscriptStore
+
+	publisher infrastructure.Publisher
 
 	config *config.Config
 	logger logger.Logger
 
 	newCmd       func(string) infrastructure.Cmd
-	newConnector func(infrastructure.Cmd, logger.Logger) infrastructure.Connector
+	newConnector func(engine.ID, infrastructure.Cmd, logger.Logger) infrastructure.Connector
+
+	// actors holds the actor of each engine. Every control of the engine
+	// goes through the actor, so that they are never interleaved.
+	actorsMu sync.Mutex
+	actors   map[engine.ID]*engineActor
 }
 
 func (service *engineService) Connect(id engine.ID) error {
@@ -82,42 +132,272 @@ func (service *engineService) Connect(id engine.ID) error {
 	}
 
 	egn := engine.New(id, path)
+	egn.SetRestartWindow(service.config.App.Restart.Window)
 	cmd := service.newCmd(path)
 	cmd.Chdir(filepath.Dir(path))
-	conn := service.newConnector(cmd, service.logger)
+	conn := service.newConnector(id, cmd, service.logger)
 	if err := service.engineStore.Insert(egn, conn); err != nil {
 		return framework.NewInternalServerError("insert new engine", err)
 	}
 
-	return service.withControl(id, func(service EngineControlService) error {
+	control := &engineControlService{
+		engine:          egn,
+		connector:       conn,
+		engineInfoStore: service.engineInfoStore,
+		gameStore:       service.gameStore,
+		transcriptStore: service.transcriptStore,
+		publisher:       service.publisher,
+		logger:          service.logger,
+	}
+	a := newEngineActor(control)
+	service.actorsMu.Lock()
+	service.actors[id] = a
+	service.actorsMu.Unlock()
+
+	err := service.withControl(id, func(service EngineControlService) error {
 		return service.Connect()
 	})
+	if err != nil {
+		return err
+	}
+
+	go service.watchExit(a, conn)
+	go service.watchIdle(a, egn)
+	go service.watchHealth(a)
+	return nil
+}
+
+// watchHealth checks the health of the engine periodically
+// until the actor stops.
+func (service *engineService) watchHealth(a *engineActor) {
+	conf := service.config.App.Health
+	if conf.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+		}
+
+		err := a.do(func(ecs *engineControlService) error {
+			return ecs.checkHealth(conf)
+		})
+		if errors.Is(err, errActorStopped) {
+			return
+		}
+		if err != nil {
+			service.logger.Error("[HealthCheck]", zap.Error(err))
+		}
+	}
+}
+
+// watchIdle closes the engine when it has been idle longer than the
+// configured timeout, until the actor stops.
+func (service *engineService) watchIdle(a *engineActor, egn *engine.Engine) {
+	id := egn.GetID()
+	timeout := service.config.App.Idle.TimeoutOf(id.String())
+	if timeout <= 0 {
+		return
+	}
+
+	interval := idleCheckInterval
+	if timeout < interval {
+		interval = timeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+		}
+
+		closed := false
+		err := a.do(func(ecs *engineControlService) error {
+			if egn.GetState() == engine.Thinking || egn.IsWatched() ||
+				time.Since(egn.IdleSince()) < timeout {
+				return nil
+			}
+			service.logger.Info("[CloseIdleEngine]", zap.String("engine id", id.String()))
+			if err := ecs.Close(closeTimeout); err != nil {
+				return err
+			}
+			closed = true
+			return service.engineStore.Delete(id)
+		})
+		if errors.Is(err, errActorStopped) {
+			return
+		}
+		if err != nil {
+			service.logger.Error("[CloseIdleEngine]", zap.Error(err))
+		}
+		if closed {
+			service.removeActor(id)
+			service.closed(id, event.ReasonIdle)
+			return
+		}
+	}
+}
+
+// watchExit waits for the engine process to exit, and when it was not
+// requested, marks the engine crashed and restarts it if configured.
+func (service *engineService) watchExit(a *engineActor, conn infrastructure.Connector) {
+	<-conn.Exited()
+
+	code, requested := conn.ExitStatus()
+	if requested {
+		return
+	}
+
+	err := a.do(func(ecs *engineControlService) error {
+		thinking := ecs.engine.GetState() == engine.Thinking
+		ecs.crashed(code, conn.Stderr())
+		return service.restart(a, ecs, thinking)
+	})
+	if err != nil {
+		service.logger.Error("[RestartEngine]", zap.Error(err))
+	}
+}
+
+// restart starts new process of the crashed engine within the restart budget.
+// This must be called on the actor's goroutine.
+func (service *engineService) restart(a *engineActor, ecs *engineControlService, thinking bool) error {
+	conf := service.config.App.Restart
+	egn := ecs.engine
+	id := egn.GetID()
+
+	if !conf.Enabled {
+		return nil
+	}
+
+	now := time.Now()
+	if egn.CountRestarts(now) >= conf.MaxRestarts {
+		service.logger.Warn("[RestartEngine] restart budget exhausted", zap.String("engine id", id.String()))
+		return nil
+	}
+	egn.AddRestart(now)
+	service.logger.Info("[RestartEngine]", zap.String("engine id", id.String()))
+
+	path := egn.GetPath()
+	cmd := service.newCmd(path)
+	cmd.Chdir(filepath.Dir(path))
+	conn := service.newConnector(id, cmd, service.logger)
+	if err := service.engineStore.ReplaceConnector(id, conn); err != nil {
+		return framework.NewInternalServerError("replace connector", err)
+	}
+	ecs.connector = conn
+
+	// Connect overwrites the options with default values
+	options := egn.GetOptions().Copy()
+	if err := ecs.restore(options, thinking); err != nil {
+		// quit the new process, and leave the engine crashed
+		if e := ecs.Close(closeTimeout); e != nil {
+			service.logger.Warn("[RestartEngine] close", zap.Error(e))
+		}
+		ecs.setState(engine.Crashed)
+		return err
+	}
+
+	go service.watchExit(a, conn)
+	return nil
 }
 
 func (service *engineService) Close(id engine.ID) error {
-	return service.withControl(id, func(ecs EngineControlService) error {
+	return service.close(id, closeTimeout)
+}
+
+func (service *engineService) close(id engine.ID, timeout time.Duration) error {
+	err := service.withControl(id, func(ecs EngineControlService) error {
+		// the engine is closed even if it failed to stop thinking
 		if err := ecs.Stop(); err != nil {
-			return err
+			service.logger.Warn(
+				"[Close] failed to stop engine",
+				zap.String("engine id", id.String()),
+				zap.Error(err),
+			)
 		}
-		if err := ecs.Close(); err != nil {
+		if err := ecs.Close(timeout); err != nil {
 			return err
 		}
 		return service.engineStore.Delete(id)
 	})
+	if err != nil {
+		return err
+	}
+
+	service.removeActor(id)
+	service.closed(id, event.ReasonRequested)
+	return nil
+}
+
+// closed notifies the subscribers that the engine was closed,
+// and ends their subscriptions and the watchers of the result.
+func (service *engineService) closed(id engine.ID, reason string) {
+	service.publisher.Publish(event.NewClosed(id, reason))
+	service.publisher.Close(id)
+	service.engineInfoStore.CloseWatchers(id)
+}
+
+// removeActor stops the actor of the engine and removes it.
+func (service *engineService) removeActor(id engine.ID) {
+	service.actorsMu.Lock()
+	if a, ok := service.actors[id]; ok {
+		a.stop()
+		delete(service.actors, id)
+	}
+	service.actorsMu.Unlock()
 }
 
-func (service *engineService) CloseAll() error {
-	for _, id := range service.engineStore.FindAllKeys() {
-		if err := service.Close(id); err != nil {
-			return framework.NewInternalServerError("delete engine at engine service. ID="+id.String(), err)
+// CloseAll closes all engines concurrently. Each engine is killed when it
+// does not exit within the timeout after quit, and the engines which failed
+// to exit are reported in the error.
+func (service *engineService) CloseAll(timeout time.Duration) error {
+	ids := service.engineStore.FindAllKeys()
+	errs := make([]error, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id engine.ID) {
+			defer wg.Done()
+			errs[i] = service.close(id, timeout)
+		}(i, id)
+	}
+	wg.Wait()
+
+	var failed []string
+	var last error
+	for i, err := range errs {
+		if err != nil {
+			service.logger.Error(
+				"[CloseAll] failed to close engine",
+				zap.String("engine id", ids[i].String()),
+				zap.Error(err),
+			)
+			failed = append(failed, ids[i].String())
+			last = err
 		}
 	}
+	if len(failed) != 0 {
+		return framework.NewInternalServerError(
+			"failed to close engines. IDs="+strings.Join(failed, ","),
+			last,
+		)
+	}
 	return nil
 }
 
-func (service *engineService) Start(id engine.ID) error {
+func (service *engineService) Start(id engine.ID, limit *usi.SearchLimit) error {
 	return service.withControl(id, func(service EngineControlService) error {
-		return service.Start()
+		return service.Start(limit)
 	})
 }
 
@@ -127,11 +407,150 @@ func (service *engineService) Stop(id engine.ID) error {
 	})
 }
 
+// StopAndWait stops the engine and waits for the best move until timeout.
+// When the engine is not thinking, returns the last best move.
+func (service *engineService) StopAndWait(
+	id engine.ID,
+	timeout time.Duration,
+) (*usi.BestMove, error) {
+	egn, _, ok := service.engineStore.Find(id)
+	if !ok {
+		return nil, framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
+	}
+
+	_, rev := service.engineInfoStore.FindAllWithRevision(id)
+	changes, ch, unwatch := service.engineInfoStore.Watch(id, rev)
+	defer unwatch()
+
+	// check the state and stop at once, so that the search
+	// does not start or finish between them
+	thinking := false
+	err := service.withControl(id, func(ecs EngineControlService) error {
+		thinking = egn.GetState() == engine.Thinking
+		if !thinking {
+			return nil
+		}
+		return ecs.Stop()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !thinking {
+		if bm, ok := service.engineInfoStore.FindBestMove(id); ok {
+			return bm, nil
+		}
+		return nil, framework.NewNotFoundError("no bestmove. ID="+id.String(), nil)
+	}
+
+	c, err := waitChange(rev, changes, ch, usi.SetBestMove, timeout)
+	if err != nil {
+		return nil, framework.NewInternalServerError("wait for bestmove", err)
+	}
+	return c.BestMove, nil
+}
+
+// Mate starts mate search and waits for the result until wait expires.
+// When expired, stops the search and waits for the engine to answer.
+// The timeout is given to the engine in milliseconds. 0 means infinite.
+func (service *engineService) Mate(
+	id engine.ID,
+	timeout int,
+	wait time.Duration,
+) (*usi.Checkmate, error) {
+	if !service.engineStore.Exists(id) {
+		return nil, framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
+	}
+
+	_, rev := service.engineInfoStore.FindAllWithRevision(id)
+	changes, ch, unwatch := service.engineInfoStore.Watch(id, rev)
+	defer unwatch()
+
+	err := service.withControl(id, func(ecs EngineControlService) error {
+		return ecs.Mate(timeout)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if c, err := waitChange(rev, changes, ch, usi.SetCheckmate, wait); err == nil {
+		return c.Checkmate, nil
+	}
+
+	if err := service.Stop(id); err != nil {
+		return nil, err
+	}
+
+	c, err := waitChange(rev, nil, ch, usi.SetCheckmate, stopWaitTimeout)
+	if err != nil {
+		return nil, framework.NewInternalServerError("wait for checkmate", err)
+	}
+	return c.Checkmate, nil
+}
+
+// waitChange waits for a change of the type, which is newer than the revision,
+// from the given changes and the channel.
+func waitChange(
+	rev uint64,
+	changes []*usi.ResultChange,
+	ch <-chan *usi.ResultChange,
+	typ usi.ChangeType,
+	timeout time.Duration,
+) (*usi.ResultChange, error) {
+	for _, c := range changes {
+		if c.ID > rev && c.Type == typ {
+			return c, nil
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case c, ok := <-ch:
+			if !ok {
+				return nil, errors.New("stopped watching result")
+			}
+			if c.Type == typ {
+				return c, nil
+			}
+		case <-timer.C:
+			return nil, errors.New("timeout waiting for " + string(typ))
+		}
+	}
+}
+
+// GetStatus returns the summary of the engine. This is not counted as
+// an access, so that monitoring the engine does not keep it alive.
+func (service *engineService) GetStatus(id engine.ID) (*engine.Status, error) {
+	egn, _, ok := service.engineStore.Find(id)
+	if !ok {
+		return nil, framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
+	}
+	return egn.GetStatus(), nil
+}
+
+// GetHealth returns the result of the health checks of the engine.
+// Like GetStatus, this is not counted as an access.
+func (service *engineService) GetHealth(id engine.ID) (*engine.Health, error) {
+	egn, _, ok := service.engineStore.Find(id)
+	if !ok {
+		return nil, framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
+	}
+	health := egn.GetHealth()
+	if health == nil {
+		return nil, framework.NewNotFoundError("engine is not connected. ID="+id.String(), nil)
+	}
+	return health, nil
+}
+
 func (service *engineService) GetOptions(id engine.ID) (*engine.Options, error) {
 	egn, _, ok := service.engineStore.Find(id)
 	if !ok {
 		return nil, framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
 	}
+	egn.Access(time.Now())
 	return egn.GetOptions(), nil
 }
 
@@ -166,6 +585,7 @@ func (service *engineService) UpdateTextOption(id engine.ID, txt *engine.Text) e
 }
 
 func (service *engineService) GetCurrentPosition(id engine.ID) (*shogi.Position, bool) {
+	service.access(id)
 	return service.gameStore.FindPosition(id)
 }
 
@@ -174,24 +594,245 @@ func (service *engineService) UpdatePosition(id engine.ID, pos *shogi.Position)
 		if err := ecs.UpdatePosition(pos); err != nil {
 			return err
 		}
-		service.gameStore.UpsertPosition(id, pos)
+		service.publisher.Publish(event.NewPosition(id, pos))
+		return nil
+	})
+}
+
+func (service *engineService) GetGame(id engine.ID) (*game.Tree, bool) {
+	service.access(id)
+	return service.gameStore.FindGame(id)
+}
+
+// SetGame starts new game from the initial position with the moves,
+// and sets the position after the moves to the engine.
+func (service *engineService) SetGame(
+	id engine.ID,
+	initial *shogi.Position,
+	moves []*shogi.Move,
+) (*shogi.Position, error) {
+	return service.changePosition(id, func(ecs EngineControlService) (*shogi.Position, error) {
+		return ecs.SetGame(initial, moves)
+	})
+}
+
+// ApplyMove applies the move to the current position, and sets the new
+// position to the engine. Returns the new position.
+func (service *engineService) ApplyMove(id engine.ID, move *shogi.Move) (*shogi.Position, error) {
+	return service.changePosition(id, func(ecs EngineControlService) (*shogi.Position, error) {
+		return ecs.ApplyMove(move)
+	})
+}
+
+// Undo moves the current position back by one move.
+func (service *engineService) Undo(id engine.ID) (*shogi.Position, error) {
+	return service.changePosition(id, EngineControlService.Undo)
+}
+
+// Redo moves the current position forward by one undone move.
+func (service *engineService) Redo(id engine.ID) (*shogi.Position, error) {
+	return service.changePosition(id, EngineControlService.Redo)
+}
+
+// Jump moves the current position to the ply of the game.
+func (service *engineService) Jump(id engine.ID, ply int) (*shogi.Position, error) {
+	return service.changePosition(id, func(ecs EngineControlService) (*shogi.Position, error) {
+		return ecs.Jump(ply)
+	})
+}
+
+// SelectNode moves the current position to the node of the game tree.
+func (service *engineService) SelectNode(id engine.ID, node game.NodeID) (*shogi.Position, error) {
+	return service.changePosition(id, func(ecs EngineControlService) (*shogi.Position, error) {
+		return ecs.SelectNode(node)
+	})
+}
+
+// DeleteNode deletes the node of the game tree and its descendants.
+// The current position moves to the parent if it is deleted.
+func (service *engineService) DeleteNode(id engine.ID, node game.NodeID) (*game.Tree, error) {
+	var tree *game.Tree
+	err := service.withControl(id, func(ecs EngineControlService) error {
+		t, pos, err := ecs.DeleteNode(node)
+		if err != nil {
+			return err
+		}
+		tree = t
+		if pos != nil {
+			service.publisher.Publish(event.NewPosition(id, pos))
+		}
 		return nil
 	})
+	return tree, err
+}
+
+// PromoteNode makes the node of the game tree the main line.
+func (service *engineService) PromoteNode(id engine.ID, node game.NodeID) (*game.Tree, error) {
+	var tree *game.Tree
+	err := service.withControl(id, func(ecs EngineControlService) (err error) {
+		tree, err = ecs.PromoteNode(node)
+		return err
+	})
+	return tree, err
+}
+
+// CommentNode sets the comment to the node of the game tree.
+func (service *engineService) CommentNode(id engine.ID, node game.NodeID, comment string) (*game.Tree, error) {
+	var tree *game.Tree
+	err := service.withControl(id, func(ecs EngineControlService) (err error) {
+		tree, err = ecs.CommentNode(node, comment)
+		return err
+	})
+	return tree, err
+}
+
+// changePosition executes the block which changes the current position,
+// and publishes the new position.
+func (service *engineService) changePosition(
+	id engine.ID,
+	block func(EngineControlService) (*shogi.Position, error),
+) (*shogi.Position, error) {
+	var pos *shogi.Position
+	err := service.withControl(id, func(ecs EngineControlService) error {
+		next, err := block(ecs)
+		if err != nil {
+			return err
+		}
+		pos = next
+		service.publisher.Publish(event.NewPosition(id, pos))
+		return nil
+	})
+	return pos, err
 }
 
 func (service *engineService) GetResult(id engine.ID) usi.Result {
+	service.access(id)
 	return service.engineInfoStore.FindAll(id)
 }
 
+func (service *engineService) GetBestMove(id engine.ID) (*usi.BestMove, bool) {
+	service.access(id)
+	return service.engineInfoStore.FindBestMove(id)
+}
+
+func (service *engineService) GetMessages(id engine.ID) []*usi.Message {
+	service.access(id)
+	return service.engineInfoStore.FindMessages(id)
+}
+
+func (service *engineService) GetTranscript(id engine.ID) []*usi.TranscriptLine {
+	service.access(id)
+	return service.transcriptStore.FindAll(id)
+}
+
+// WaitResult blocks while match returns true for the revision of the result,
+// until the result changes, timeout or ctx is done.
+// Returns the result and its revision.
+func (service *engineService) WaitResult(
+	ctx context.Context,
+	id engine.ID,
+	match func(revision uint64) bool,
+	timeout time.Duration,
+) (usi.Result, uint64) {
+	service.access(id)
+	res, rev := service.engineInfoStore.FindAllWithRevision(id)
+	if !match(rev) || timeout <= 0 {
+		return res, rev
+	}
+
+	changes, ch, unwatch := service.engineInfoStore.Watch(id, rev)
+	defer unwatch()
+
+	for _, c := range changes {
+		if c.ID > rev {
+			// changed between finding and watching
+			return service.engineInfoStore.FindAllWithRevision(id)
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ch:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return service.engineInfoStore.FindAllWithRevision(id)
+}
+
+func (service *engineService) Subscribe(id engine.ID) (<-chan *event.Event, func(), error) {
+	egn, _, ok := service.engineStore.Find(id)
+	if !ok {
+		return nil, nil, framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
+	}
+	ch, unsubscribe := service.publisher.Subscribe(id)
+
+	// the engine may have been closed before subscribing,
+	// then the subscription would never be closed
+	if !service.engineStore.Exists(id) {
+		unsubscribe()
+		return nil, nil, framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
+	}
+	return ch, watch(egn, unsubscribe), nil
+}
+
+func (service *engineService) WatchResult(
+	id engine.ID,
+	lastID uint64,
+) ([]*usi.ResultChange, <-chan *usi.ResultChange, func(), error) {
+	egn, _, ok := service.engineStore.Find(id)
+	if !ok {
+		return nil, nil, nil, framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
+	}
+	changes, ch, unwatch := service.engineInfoStore.Watch(id, lastID)
+
+	// the engine may have been closed before watching, as same as Subscribe
+	if !service.engineStore.Exists(id) {
+		unwatch()
+		return nil, nil, nil, framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
+	}
+	return changes, ch, watch(egn, unwatch), nil
+}
+
+// watch records the open stream of the engine, so that the engine is not
+// closed as idle while it is open. The returned func ends the stream,
+// and it can be called more than once.
+func watch(egn *engine.Engine, end func()) func() {
+	egn.Watch()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			end()
+			egn.Unwatch(time.Now())
+		})
+	}
+}
+
+// access records the API access to the engine.
+// It returns false if the engine does not exist.
+func (service *engineService) access(id engine.ID) bool {
+	egn, _, ok := service.engineStore.Find(id)
+	if ok {
+		egn.Access(time.Now())
+	}
+	return ok
+}
+
 func (service *engineService) withControl(
 	id engine.ID,
 	block func(EngineControlService) error,
 ) error {
-	egn, conn, ok := service.engineStore.Find(id)
-	if !ok {
+	service.actorsMu.Lock()
+	a, ok := service.actors[id]
+	service.actorsMu.Unlock()
+	if !ok || !service.access(id) {
 		return framework.NewNotFoundError("no such engine. ID="+id.String(), nil)
 	}
 
-	s := NewEngineControlService(egn, conn, service.engineInfoStore, service.logger)
-	return block(s)
+	err := a.do(func(ecs *engineControlService) error { return block(ecs) })
+	if errors.Is(err, errActorStopped) {
+		return framework.NewNotFoundError("engine has been closed. ID="+id.String(), err)
+	}
+	return err
 }
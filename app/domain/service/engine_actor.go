@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"sync"
+)
+
+// errActorStopped is returned when a command is given to the stopped actor.
+var errActorStopped = errors.New("engine actor has been stopped")
+
+// engineActor owns the control of one engine, and runs given commands
+// one by one on its own goroutine, so that writes to the engine and
+// state transitions are never interleaved.
+type engineActor struct {
+	control  *engineControlService
+	commands chan *actorCommand
+	done     chan struct{}
+	once     sync.Once
+}
+
+type actorCommand struct {
+	block func(*engineControlService) error
+	reply chan error
+}
+
+// newEngineActor starts new actor for the control service.
+func newEngineActor(control *engineControlService) *engineActor {
+	a := &engineActor{
+		control:  control,
+		commands: make(chan *actorCommand),
+		done:     make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *engineActor) run() {
+	for {
+		select {
+		case c := <-a.commands:
+			// the command may be accepted together with stop
+			select {
+			case <-a.done:
+				c.reply <- errActorStopped
+			default:
+				c.reply <- c.block(a.control)
+			}
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// do runs the block on the actor's goroutine, and waits for it.
+func (a *engineActor) do(block func(*engineControlService) error) error {
+	c := &actorCommand{block: block, reply: make(chan error, 1)}
+
+	select {
+	case a.commands <- c:
+	case <-a.done:
+		return errActorStopped
+	}
+
+	// the reply is always sent once the command is accepted
+	return <-c.reply
+}
+
+// stop stops the actor. Commands given after this, or waiting for the
+// running command, fail with errActorStopped. The running command completes.
+func (a *engineActor) stop() {
+	a.once.Do(func() { close(a.done) })
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEngineActor_Do(t *testing.T) {
+	a := newEngineActor(&engineControlService{})
+	defer a.stop()
+
+	// commands given concurrently are never interleaved,
+	// and each caller receives the result of its own command
+	var (
+		running, overlaps int
+		order             []int
+		wg                sync.WaitGroup
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := a.do(func(*engineControlService) error {
+				running++
+				if running > 1 {
+					overlaps++
+				}
+				time.Sleep(time.Millisecond)
+				order = append(order, i)
+				running--
+				return fmt.Errorf("%d", i)
+			})
+			if err == nil || err.Error() != fmt.Sprint(i) {
+				t.Errorf("[engineActor.do] Index: %d, unexpected result: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if overlaps != 0 || len(order) != 20 {
+		t.Errorf("[engineActor.do] commands were interleaved. overlaps=%d, order=%v", overlaps, order)
+	}
+
+	// commands given in order run in order
+	order = nil
+	for i := 0; i < 5; i++ {
+		i := i
+		_ = a.do(func(*engineControlService) error {
+			order = append(order, i)
+			return nil
+		})
+	}
+	if fmt.Sprint(order) != "[0 1 2 3 4]" {
+		t.Errorf("[engineActor.do] unexpected order: %v", order)
+	}
+}
+
+func TestEngineActor_Stop(t *testing.T) {
+	a := newEngineActor(&engineControlService{})
+
+	// the running command
+	release := make(chan struct{})
+	started := make(chan struct{})
+	running := make(chan error)
+	go func() {
+		running <- a.do(func(*engineControlService) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// the command waiting for the running one
+	ran := false
+	pending := make(chan error)
+	go func() {
+		pending <- a.do(func(*engineControlService) error {
+			ran = true
+			return nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	a.stop()
+	a.stop() // stopping twice is allowed
+
+	if err := <-pending; !errors.Is(err, errActorStopped) {
+		t.Errorf("[engineActor.stop] expected errActorStopped for the pending command, but got %v", err)
+	}
+
+	close(release)
+	if err := <-running; err != nil {
+		t.Errorf("[engineActor.stop] the running command should complete, but got %v", err)
+	}
+	if ran {
+		t.Error("[engineActor.stop] the pending command ran after stop")
+	}
+
+	err := a.do(func(*engineControlService) error {
+		t.Error("[engineActor.stop] the command ran after stop")
+		return nil
+	})
+	if !errors.Is(err, errActorStopped) {
+		t.Errorf("[engineActor.stop] expected errActorStopped after stop, but got %v", err)
+	}
+}
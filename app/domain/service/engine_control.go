@@ -2,23 +2,31 @@ package service
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/murosan/shogi-board-server/app/domain/config"
 	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/event"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
 	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
 	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
 	"github.com/murosan/shogi-board-server/app/domain/framework"
 	"github.com/murosan/shogi-board-server/app/domain/infrastructure"
 	"github.com/murosan/shogi-board-server/app/domain/infrastructure/store"
+	"github.com/murosan/shogi-board-server/app/lib/rules"
 	"github.com/murosan/shogi-board-server/app/lib/usi/convert"
 	"github.com/murosan/shogi-board-server/app/lib/usi/parse"
 	"github.com/murosan/shogi-board-server/app/logger"
 )
 
-const (
+// timeouts of engine responses. These are variables to be shortened in tests.
+var (
 	connectTimeout = time.Second * 5
 	closeTimeout   = time.Second * 5
 	readyTimeout   = time.Second * 5
@@ -28,13 +36,18 @@ var (
 	namePrefix   = []byte("id name ")
 	authorPrefix = []byte("id author ")
 	optionPrefix = []byte("option ")
+
+	infoStringPrefix = []byte("info string")
+	bestMovePrefix   = []byte("bestmove")
+	checkmatePrefix  = []byte("checkmate")
 )
 
 // EngineControlService is a service for controlling engine.
 type EngineControlService interface {
 	Connect() error
-	Close() error
-	Start() error
+	Close(timeout time.Duration) error
+	Start(*usi.SearchLimit) error
+	Mate(timeout int) error
 	Stop() error
 	UpdateButtonOption(*engine.Button) error
 	UpdateCheckOption(*engine.Check) error
@@ -42,19 +55,34 @@ type EngineControlService interface {
 	UpdateSelectOption(*engine.Select) error
 	UpdateTextOption(*engine.Text) error
 	UpdatePosition(*shogi.Position) error
+	SetGame(initial *shogi.Position, moves []*shogi.Move) (*shogi.Position, error)
+	ApplyMove(*shogi.Move) (*shogi.Position, error)
+	Undo() (*shogi.Position, error)
+	Redo() (*shogi.Position, error)
+	Jump(ply int) (*shogi.Position, error)
+	SelectNode(game.NodeID) (*shogi.Position, error)
+	DeleteNode(game.NodeID) (*game.Tree, *shogi.Position, error)
+	PromoteNode(game.NodeID) (*game.Tree, error)
+	CommentNode(id game.NodeID, comment string) (*game.Tree, error)
 }
 
 // NewEngineControlService returns new EngineControlService.
 func NewEngineControlService(
 	engine *engine.Engine,
 	connector infrastructure.Connector,
-	store store.EngineInfoStore,
+	engineInfoStore store.EngineInfoStore,
+	gameStore store.GameStore,
+	transcriptStore store.TranscriptStore,
+	publisher infrastructure.Publisher,
 	logger logger.Logger,
 ) EngineControlService {
 	return &engineControlService{
 		engine:          engine,
 		connector:       connector,
-		engineInfoStore: store,
+		engineInfoStore: engineInfoStore,
+		gameStore:       gameStore,
+		transcriptStore: transcriptStore,
+		publisher:       publisher,
 		logger:          logger,
 	}
 }
@@ -63,216 +91,519 @@ type engineControlService struct {
 	engine          *engine.Engine
 	connector       infrastructure.Connector
 	engineInfoStore store.EngineInfoStore
+	gameStore       store.GameStore
+	transcriptStore store.TranscriptStore
+	publisher       infrastructure.Publisher
 	logger          logger.Logger
+
+	// searchMu guards the search state, that is the engine state and the
+	// pending stops, which is changed by both commands and outputs of the
+	// engine. Commands hold it only while writing, never while waiting for
+	// the engine, so that outputs are handled without waiting for commands.
+	searchMu sync.Mutex
 }
 
 func (service *engineControlService) Connect() error {
 	egn := service.engine
 	service.logger.Info("[Connecting to Engine]", zap.String("engine id", egn.GetID().String()))
 
+	// record all outputs from the beginning, in order with the sent lines
+	service.connector.Record(service.record)
+
 	if err := service.connector.Connect(); err != nil {
 		return framework.NewInternalServerError("call connect", err)
 	}
 
+	lines, unsubscribe := service.connector.Subscribe(
+		infrastructure.LineID,
+		infrastructure.LineOption,
+		infrastructure.LineUSIOK,
+		infrastructure.LineReadyOK,
+		infrastructure.LineInfo,
+	)
+	defer unsubscribe()
+
 	if err := service.write(usi.Command.USI); err != nil {
 		return framework.NewInternalServerError("write "+string(usi.Command.USI), err)
 	}
 
-	done := make(chan struct{})
+	if err := service.waitFor(lines, infrastructure.LineUSIOK, connectTimeout); err != nil {
+		return framework.NewInternalServerError(
+			"connect timeout. failed to receive usiok"+service.stderrMessage(),
+			err,
+		)
+	}
 
-	go service.connector.OnReceive(func(b []byte) bool {
-		service.logger.Info("[EngineOutput]", zap.ByteString("value", b))
-		if bytes.Equal(b, usi.Response.OK) {
-			done <- struct{}{}
-			return true
-		}
+	readyAt := time.Now()
+	if err := service.write(usi.Command.IsReady); err != nil {
+		return framework.NewInternalServerError("write "+string(usi.Command.IsReady), err)
+	}
 
-		if bytes.Equal(b, usi.Response.ReadyOK) {
-			close(done)
-			return false
-		}
+	if err := service.waitFor(lines, infrastructure.LineReadyOK, readyTimeout); err != nil {
+		return framework.NewInternalServerError(
+			"connect timeout. failed to receive readyok"+service.stderrMessage(),
+			err,
+		)
+	}
+	now := time.Now()
+	egn.SetHealth(engine.Health{}.Succeeded(now.Sub(readyAt), now))
 
-		// set name if s starts with 'id name '
-		if bytes.HasPrefix(b, namePrefix) {
-			bn := bytes.TrimLeft(b, string(namePrefix))
-			sn := string(bytes.TrimSpace(bn))
-			egn.SetName(sn)
-			service.logger.Info("[EngineName]", zap.String("value", sn))
-			return true
-		}
+	// catch search outputs on background until the engine is closed
+	results, _ := service.connector.Subscribe(
+		infrastructure.LineInfo,
+		infrastructure.LineBestMove,
+		infrastructure.LineCheckmate,
+	)
+	go service.receive(results)
 
-		// set author if s starts with 'id author '
-		if bytes.HasPrefix(b, authorPrefix) {
-			ba := bytes.TrimLeft(b, string(authorPrefix))
-			sa := string(bytes.TrimSpace(ba))
-			egn.SetAuthor(sa)
-			service.logger.Info("[EngineAuthor]", zap.String("value", sa))
-			return true
-		}
+	service.setState(engine.Connected)
+	return nil
+}
+
+// checkHealth sends 'isready' and updates the health of the engine by the
+// answer. Only the engine waiting for commands is checked. When the engine
+// becomes unhealthy and conf.Restart is true, the process is killed.
+func (service *engineControlService) checkHealth(conf config.Health) error {
+	egn := service.engine
+	if s := egn.GetState(); s != engine.Connected && s != engine.StandBy {
+		return nil
+	}
+
+	lines, unsubscribe := service.connector.Subscribe(infrastructure.LineReadyOK)
+	defer unsubscribe()
 
-		// parse option
-		if bytes.HasPrefix(b, optionPrefix) {
-			s := string(b)
-			switch {
-			case strings.Contains(s, parse.TypeButton):
-				opt, err := parse.Button(s)
-				if err != nil {
-					service.logger.Error("parse button", zap.Error(err))
-				}
-				service.logger.Info("parsed button", zap.Any("value", opt))
-				egn.GetOptions().PutButton(opt)
-
-			case strings.Contains(s, parse.TypeCheck):
-				opt, err := parse.Check(s)
-				if err != nil {
-					service.logger.Error("parse check", zap.Error(err))
-				}
-				service.logger.Info("parsed check", zap.Any("value", opt))
-				egn.GetOptions().PutCheck(opt)
-
-			case strings.Contains(s, parse.TypeRange):
-				opt, err := parse.Range(s)
-				if err != nil {
-					service.logger.Error("parse range", zap.Error(err))
-				}
-				service.logger.Info("parsed range", zap.Any("value", opt))
-				egn.GetOptions().PutRange(opt)
-
-			case strings.Contains(s, parse.TypeSelect):
-				opt, err := parse.Select(s)
-				if err != nil {
-					service.logger.Error("parse select", zap.Error(err))
-				}
-				service.logger.Info("parsed select", zap.Any("value", opt))
-				egn.GetOptions().PutSelect(opt)
-
-			case strings.Contains(s, parse.TypeString):
-				opt, err := parse.TextFromStringType(s)
-				if err != nil {
-					service.logger.Error("parse string", zap.Error(err))
-				}
-				service.logger.Info("parsed string", zap.Any("value", opt))
-				egn.GetOptions().PutText(opt)
-
-			case strings.Contains(s, parse.TypeFilename):
-				opt, err := parse.TextFromFilenameType(s)
-				if err != nil {
-					service.logger.Error("parse filename", zap.Error(err))
-				}
-				service.logger.Info("parsed filename", zap.Any("value", opt))
-				egn.GetOptions().PutText(opt)
+	health := egn.GetHealth()
+	if health == nil {
+		health = &engine.Health{Healthy: true}
+	}
+	start := time.Now()
+
+	err := service.write(usi.Command.IsReady)
+	if err == nil {
+		err = service.waitFor(lines, infrastructure.LineReadyOK, conf.Timeout)
+	}
+
+	now := time.Now()
+	if err == nil {
+		egn.SetHealth(health.Succeeded(now.Sub(start), now))
+		return nil
+	}
+
+	next := health.Failed(conf.MaxFailures, now)
+	egn.SetHealth(next)
+	service.logger.Warn(
+		"[HealthCheck] failed",
+		zap.String("engine id", egn.GetID().String()),
+		zap.Int("failures", next.Failures),
+		zap.Error(err),
+	)
+
+	if !next.Healthy && conf.Restart {
+		service.logger.Warn("[HealthCheck] killing unhealthy engine", zap.String("engine id", egn.GetID().String()))
+		return service.connector.Kill()
+	}
+	return nil
+}
+
+// stderrMessage returns the last lines of the engine's stderr for error messages.
+func (service *engineControlService) stderrMessage() string {
+	lines := service.connector.Stderr()
+	if len(lines) == 0 {
+		return ""
+	}
+	return ". stderr=" + strings.Join(lines, "\n")
+}
+
+// waitFor handles initializing outputs of the engine until the line of
+// given type is received.
+func (service *engineControlService) waitFor(
+	lines <-chan []byte,
+	typ infrastructure.LineType,
+	timeout time.Duration,
+) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case b, ok := <-lines:
+			if !ok {
+				return errors.New("engine output has been closed")
 			}
+			service.logger.Info("[EngineOutput]", zap.ByteString("value", b))
+			if infrastructure.TypeOf(b) == typ {
+				return nil
+			}
+			service.handleInitOutput(b)
+		case <-timer.C:
+			return errors.New("timeout waiting for " + string(typ))
 		}
+	}
+}
 
-		return true
-	})
+// handleInitOutput handles an output of the engine while initializing.
+func (service *engineControlService) handleInitOutput(b []byte) {
+	egn := service.engine
 
-	if err := service.timeout(done, connectTimeout); err != nil {
-		close(done)
-		return framework.NewInternalServerError("connect timeout. failed to receive usiok", err)
+	// engines report loading progress and so on while initializing
+	if bytes.HasPrefix(b, infoStringPrefix) {
+		service.receiveMessage(b)
+		return
 	}
 
-	if err := service.write(usi.Command.IsReady); err != nil {
-		return framework.NewInternalServerError("write "+string(usi.Command.IsReady), err)
+	// set name if s starts with 'id name '
+	if bytes.HasPrefix(b, namePrefix) {
+		bn := bytes.TrimLeft(b, string(namePrefix))
+		sn := string(bytes.TrimSpace(bn))
+		egn.SetName(sn)
+		service.logger.Info("[EngineName]", zap.String("value", sn))
+		return
 	}
 
-	if err := service.timeout(done, readyTimeout); err != nil {
-		return framework.NewInternalServerError("connect timeout. failed to receive readyok", err)
+	// set author if s starts with 'id author '
+	if bytes.HasPrefix(b, authorPrefix) {
+		ba := bytes.TrimLeft(b, string(authorPrefix))
+		sa := string(bytes.TrimSpace(ba))
+		egn.SetAuthor(sa)
+		service.logger.Info("[EngineAuthor]", zap.String("value", sa))
+		return
 	}
 
-	egn.SetState(engine.Connected)
-	return nil
+	// parse option
+	if bytes.HasPrefix(b, optionPrefix) {
+		s := string(b)
+		switch {
+		case strings.Contains(s, parse.TypeButton):
+			opt, err := parse.Button(s)
+			if err != nil {
+				service.logger.Error("parse button", zap.Error(err))
+			}
+			service.logger.Info("parsed button", zap.Any("value", opt))
+			egn.GetOptions().PutButton(opt)
+
+		case strings.Contains(s, parse.TypeCheck):
+			opt, err := parse.Check(s)
+			if err != nil {
+				service.logger.Error("parse check", zap.Error(err))
+			}
+			service.logger.Info("parsed check", zap.Any("value", opt))
+			egn.GetOptions().PutCheck(opt)
+
+		case strings.Contains(s, parse.TypeRange):
+			opt, err := parse.Range(s)
+			if err != nil {
+				service.logger.Error("parse range", zap.Error(err))
+			}
+			service.logger.Info("parsed range", zap.Any("value", opt))
+			egn.GetOptions().PutRange(opt)
+
+		case strings.Contains(s, parse.TypeSelect):
+			opt, err := parse.Select(s)
+			if err != nil {
+				service.logger.Error("parse select", zap.Error(err))
+			}
+			service.logger.Info("parsed select", zap.Any("value", opt))
+			egn.GetOptions().PutSelect(opt)
+
+		case strings.Contains(s, parse.TypeString):
+			opt, err := parse.TextFromStringType(s)
+			if err != nil {
+				service.logger.Error("parse string", zap.Error(err))
+			}
+			service.logger.Info("parsed string", zap.Any("value", opt))
+			egn.GetOptions().PutText(opt)
+
+		case strings.Contains(s, parse.TypeFilename):
+			opt, err := parse.TextFromFilenameType(s)
+			if err != nil {
+				service.logger.Error("parse filename", zap.Error(err))
+			}
+			service.logger.Info("parsed filename", zap.Any("value", opt))
+			egn.GetOptions().PutText(opt)
+		}
+	}
 }
 
-func (service *engineControlService) Close() error {
+// Close quits the engine, and kills the process when it does not exit
+// within the timeout.
+func (service *engineControlService) Close(timeout time.Duration) error {
 	egn := service.engine
 	service.logger.Info("[Closing Engine]", zap.String("engine name", egn.GetName()))
 
+	// the process may be alive when it failed to connect
 	if egn.GetState() == engine.NotConnected {
+		if err := service.connector.Close(timeout); err != nil {
+			return framework.NewInternalServerError("close engine", err)
+		}
 		return nil
 	}
 
+	// the process has already exited
+	if egn.GetState() == engine.Crashed {
+		_ = service.connector.Close(timeout)
+		service.setState(engine.NotConnected)
+		return nil
+	}
+
+	// the process is killed even if quit could not be written
 	if err := service.write(usi.Command.Quit); err != nil {
-		return framework.WrapError("write "+string(usi.Command.Quit), err)
+		service.logger.Warn("[Closing Engine] write "+string(usi.Command.Quit), zap.Error(err))
 	}
 
-	if err := service.connector.Close(closeTimeout); err != nil {
+	if err := service.connector.Close(timeout); err != nil {
 		return framework.NewInternalServerError("close engine", err)
 	}
 
+	service.setState(engine.NotConnected)
 	return nil
 }
 
-func (service *engineControlService) Start() error {
+func (service *engineControlService) Start(limit *usi.SearchLimit) error {
 	egn := service.engine
 	service.logger.Info("[Starting Engine]", zap.String("engine name", egn.GetName()))
 
-	if egn.GetState() == engine.NotConnected {
-		return framework.NewBadRequestError("must initialize engine first", nil)
+	if err := service.checkAlive(); err != nil {
+		return err
 	}
 
+	if err := validateSearchLimit(limit); err != nil {
+		return err
+	}
+
+	service.searchMu.Lock()
+	defer service.searchMu.Unlock()
+	return service.start(limit)
+}
+
+// start starts the search with the limit unless the engine is thinking.
+// searchMu must be held.
+func (service *engineControlService) start(limit *usi.SearchLimit) error {
+	egn := service.engine
 	if egn.GetState() == engine.Thinking {
 		return nil
 	}
 
+	egn.SetSearchLimit(limit)
+	egn.SetMating(false)
+	return service.startSearch(limit.ToUSI())
+}
+
+func (service *engineControlService) Mate(timeout int) error {
+	egn := service.engine
+	service.logger.Info("[Starting Mate Search]", zap.String("engine name", egn.GetName()))
+
+	if err := service.checkAlive(); err != nil {
+		return err
+	}
+
+	if timeout < 0 {
+		return framework.NewBadRequestError("timeout must not be negative", nil)
+	}
+
+	service.searchMu.Lock()
+	defer service.searchMu.Unlock()
+
+	// stop the current search first
+	if err := service.stop(); err != nil {
+		return err
+	}
+
+	egn.SetSearchLimit(nil)
+	egn.SetMating(true)
+	return service.startSearch(usi.MateCommand(timeout))
+}
+
+// startSearch writes the go command. searchMu must be held.
+func (service *engineControlService) startSearch(cmd []byte) error {
+	egn := service.engine
+
 	if egn.GetState() == engine.Connected {
 		if err := service.write(usi.Command.NewGame); err != nil {
 			return framework.NewInternalServerError("write "+string(usi.Command.NewGame), err)
 		}
 
-		egn.SetState(engine.StandBy)
+		service.setState(engine.StandBy)
 	}
 
 	// before start thinking, delete all consideration results
 	service.engineInfoStore.DeleteAll(egn.GetID())
 
-	// catch call engine outputs on background
-	go func() {
-		egn := service.engine
-		service.connector.OnReceive(func(b []byte) bool {
-			service.logger.Info("[EngineOutput]", zap.ByteString("message", b))
+	service.setState(engine.Thinking)
+	if err := service.write(cmd); err != nil {
+		return framework.NewInternalServerError("write "+string(cmd), nil)
+	}
 
-			// ignore 'info string' for now
-			if bytes.HasPrefix(b, []byte("info string")) {
-				return true
-			}
+	return nil
+}
 
-			if bytes.HasPrefix(b, []byte("info ")) {
-				i, mpv, err := parse.Info(string(b))
-				if err != nil {
-					service.logger.Error("[start]", zap.Error(err))
-					return true // ignore error
-				}
-
-				// service.logger.Info("[ParsedInfo]", zap.Any("value", i))
-
-				if mpv <= 1 {
-					// If mpv is less than or equal to 1, it means 'best move' usually.
-					// We need to delete when the number of candidates is reduced,
-					// for example from 5 to 2, not to be left extra information.
-					service.engineInfoStore.DeleteAll(egn.GetID())
-				}
-				if len(i.Moves) != 0 {
-					service.engineInfoStore.Upsert(egn.GetID(), mpv, i)
-				}
-			}
+// receive handles search outputs of the engine until the channel is closed.
+// Outputs are handled on this goroutine, not on the actor's, so that they
+// are never stalled by a command waiting for the engine.
+func (service *engineControlService) receive(lines <-chan []byte) {
+	for b := range lines {
+		service.logger.Info("[EngineOutput]", zap.ByteString("message", b))
 
-			return egn.GetState() == engine.Thinking
-		})
-	}()
+		// the output depends on and changes the search state
+		service.searchMu.Lock()
+		service.handleOutput(b)
+		service.searchMu.Unlock()
+	}
+}
 
-	egn.SetState(engine.Thinking)
-	if err := service.write(usi.Command.GoInf); err != nil {
-		return framework.NewInternalServerError("write "+string(usi.Command.GoInf), nil)
+// handleOutput handles a search output of the engine. searchMu must be held.
+func (service *engineControlService) handleOutput(b []byte) {
+	egn := service.engine
+
+	if bytes.HasPrefix(b, infoStringPrefix) {
+		service.receiveMessage(b)
+		return
+	}
+
+	// outputs are not expected when the engine is neither thinking
+	// nor acknowledging 'stop'
+	if egn.GetState() != engine.Thinking && !egn.HasPendingStop() {
+		service.logger.Warn("[UnexpectedOutput]", zap.ByteString("message", b))
+		return
+	}
+	egn.Think(time.Now())
+
+	isBestMove := bytes.HasPrefix(b, bestMovePrefix)
+	isCheckmate := bytes.HasPrefix(b, checkmatePrefix)
+	if isBestMove || isCheckmate {
+		// When a new search has already started after 'stop',
+		// the output belongs to the old search.
+		stale := egn.HasPendingStop() && egn.GetState() == engine.Thinking
+		finished := !egn.HasPendingStop() && egn.GetState() == engine.Thinking
+		egn.DonePendingStop()
+		if !stale && isBestMove {
+			service.receiveBestMove(b)
+		}
+		if !stale && isCheckmate {
+			service.receiveCheckmate(b)
+		}
+		if finished {
+			// the search has finished by its limits
+			service.setState(engine.StandBy)
+		}
+		return
 	}
 
+	if bytes.HasPrefix(b, []byte("info ")) {
+		// drop infos of the old search
+		if egn.HasPendingStop() {
+			return
+		}
+
+		i, mpv, err := parse.Info(string(b))
+		if err != nil {
+			service.logger.Error("[start]", zap.Error(err))
+			return // ignore error
+		}
+
+		i.ReceivedAt = time.Now()
+		if tag, ok := service.gameStore.FindPositionTag(egn.GetID()); ok {
+			i.Position = tag
+		}
+
+		// service.logger.Info("[ParsedInfo]", zap.Any("value", i))
+
+		if mpv <= 1 {
+			// If mpv is less than or equal to 1, it means 'best move' usually.
+			// We need to delete when the number of candidates is reduced,
+			// for example from 5 to 2, not to be left extra information.
+			service.engineInfoStore.DeleteAll(egn.GetID())
+		}
+		if len(i.Moves) != 0 {
+			service.engineInfoStore.Upsert(egn.GetID(), mpv, i)
+		}
+		if mpv <= 1 && i.Position != nil {
+			if eval := game.NewEvaluation(i); eval != nil {
+				service.gameStore.SetEvaluation(egn.GetID(), i.Position.Revision, eval)
+			}
+		}
+		service.publisher.Publish(event.NewInfo(egn.GetID(), mpv, i))
+	}
+}
+
+func validateSearchLimit(limit *usi.SearchLimit) error {
+	if err := limit.Validate(); err != nil {
+		return framework.NewBadRequestError("invalid search limit", err)
+	}
+	if limit == nil {
+		return nil
+	}
+	for _, m := range limit.SearchMoves {
+		if _, err := parse.Move(m); err != nil {
+			return framework.NewBadRequestError("invalid searchmoves", err)
+		}
+	}
 	return nil
 }
 
+// receiveMessage stores 'info string' message.
+func (service *engineControlService) receiveMessage(b []byte) {
+	id := service.engine.GetID()
+
+	text, err := parse.InfoString(string(b))
+	if err != nil {
+		service.logger.Error("[InfoString]", zap.Error(err))
+		return
+	}
+
+	m := &usi.Message{Text: text, ReceivedAt: time.Now()}
+	service.engineInfoStore.AppendMessage(id, m)
+	service.publisher.Publish(event.NewMessage(id, m))
+}
+
+// receiveBestMove parses 'bestmove' and stores it.
+func (service *engineControlService) receiveBestMove(b []byte) {
+	id := service.engine.GetID()
+
+	bm, err := parse.BestMove(string(b))
+	if err != nil {
+		service.logger.Error("[BestMove]", zap.Error(err))
+		return
+	}
+
+	bm.ReceivedAt = time.Now()
+	if tag, ok := service.gameStore.FindPositionTag(id); ok {
+		bm.Position = tag
+	}
+
+	service.engineInfoStore.SetBestMove(id, bm)
+	service.publisher.Publish(event.NewBestMove(id, bm))
+}
+
+// receiveCheckmate parses 'checkmate' and stores it.
+func (service *engineControlService) receiveCheckmate(b []byte) {
+	id := service.engine.GetID()
+
+	cm, err := parse.Checkmate(string(b))
+	if err != nil {
+		service.logger.Error("[Checkmate]", zap.Error(err))
+		return
+	}
+
+	cm.ReceivedAt = time.Now()
+	if tag, ok := service.gameStore.FindPositionTag(id); ok {
+		cm.Position = tag
+	}
+
+	service.engineInfoStore.SetCheckmate(id, cm)
+	service.publisher.Publish(event.NewCheckmate(id, cm))
+}
+
 func (service *engineControlService) Stop() error {
-	egn := service.engine
-	service.logger.Info("[Stopping Engine]", zap.String("engine name", egn.GetName()))
+	service.logger.Info("[Stopping Engine]", zap.String("engine name", service.engine.GetName()))
 
+	service.searchMu.Lock()
+	defer service.searchMu.Unlock()
+	return service.stop()
+}
+
+// stop writes 'stop' if the engine is thinking. searchMu must be held.
+func (service *engineControlService) stop() error {
+	egn := service.engine
 	if egn.GetState() != engine.Thinking {
 		return nil
 	}
@@ -280,8 +611,9 @@ func (service *engineControlService) Stop() error {
 	if err := service.write(usi.Command.Stop); err != nil {
 		return framework.WrapError("write "+string(usi.Command.Quit), err)
 	}
+	egn.AddPendingStop()
 
-	egn.SetState(engine.StandBy)
+	service.setState(engine.StandBy)
 	return nil
 }
 
@@ -289,20 +621,39 @@ func (service *engineControlService) UpdateButtonOption(button *engine.Button) e
 	return service.updateOption(button)
 }
 
+// UpdateCheckOption sets the option to the engine. The updated values of
+// check, range, select and text options are kept in the engine options,
+// so that they are restored when the engine restarts.
 func (service *engineControlService) UpdateCheckOption(check *engine.Check) error {
-	return service.updateOption(check)
+	if err := service.updateOption(check); err != nil {
+		return err
+	}
+	service.engine.GetOptions().PutCheck(check)
+	return nil
 }
 
 func (service *engineControlService) UpdateRangeOption(rang *engine.Range) error {
-	return service.updateOption(rang)
+	if err := service.updateOption(rang); err != nil {
+		return err
+	}
+	service.engine.GetOptions().PutRange(rang)
+	return nil
 }
 
 func (service *engineControlService) UpdateSelectOption(sel *engine.Select) error {
-	return service.updateOption(sel)
+	if err := service.updateOption(sel); err != nil {
+		return err
+	}
+	service.engine.GetOptions().PutSelect(sel)
+	return nil
 }
 
 func (service *engineControlService) UpdateTextOption(text *engine.Text) error {
-	return service.updateOption(text)
+	if err := service.updateOption(text); err != nil {
+		return err
+	}
+	service.engine.GetOptions().PutText(text)
+	return nil
 }
 
 func (service *engineControlService) updateOption(option engine.Option) error {
@@ -310,50 +661,461 @@ func (service *engineControlService) updateOption(option engine.Option) error {
 	if err := option.Validate(); err != nil {
 		return framework.NewBadRequestError("invalid option value", err)
 	}
+	if service.engine.GetState() == engine.Crashed {
+		return framework.NewBadRequestError("engine has crashed", nil)
+	}
 	return service.write([]byte(option.ToUSI()))
 }
 
 func (service *engineControlService) UpdatePosition(position *shogi.Position) error {
 	service.logger.Info("[UpdatePosition]", zap.Any("position", position))
 
-	isThinking := service.engine.GetState() == engine.Thinking
+	if service.engine.GetState() == engine.Crashed {
+		return framework.NewBadRequestError("engine has crashed", nil)
+	}
 
-	// stop thinking first
-	if isThinking {
-		if err := service.Stop(); err != nil {
-			return err
+	sfen, err := convert.SFEN(position)
+	if err != nil {
+		return framework.NewBadRequestError("invalid position", err)
+	}
+
+	return service.setPosition(convert.PositionWithMoves(sfen, nil), func(id engine.ID) {
+		service.gameStore.UpsertPosition(id, position, sfen)
+	})
+}
+
+// SetGame sets the initial position, and makes the moves from it as the
+// main line of new game tree. The position after the moves is set to the
+// engine, and returned.
+func (service *engineControlService) SetGame(initial *shogi.Position, moves []*shogi.Move) (*shogi.Position, error) {
+	service.logger.Info("[SetGame]", zap.Any("position", initial), zap.Any("moves", moves))
+
+	if service.engine.GetState() == engine.Crashed {
+		return nil, framework.NewBadRequestError("engine has crashed", nil)
+	}
+
+	board, err := rules.New(initial)
+	if err != nil {
+		return nil, framework.NewBadRequestError("invalid position", err)
+	}
+
+	tree := game.NewTree(initial)
+	for i, m := range moves {
+		valid, err := board.Validate(m)
+		if err != nil {
+			return nil, framework.NewBadRequestError(fmt.Sprintf("illegal move. index=%d", i), err)
+		}
+		if err := board.DoMove(valid); err != nil {
+			return nil, framework.NewInternalServerError("do move", err)
+		}
+		n, err := tree.Add(tree.Current, valid)
+		if err != nil {
+			return nil, framework.NewInternalServerError("add move", err)
+		}
+		if err := tree.Select(n.ID); err != nil {
+			return nil, framework.NewInternalServerError("select move", err)
 		}
 	}
 
-	b, err := convert.Position(position)
+	pos := board.Position()
+	if err := service.setGame(tree, pos); err != nil {
+		return nil, err
+	}
+	return pos, nil
+}
+
+func (service *engineControlService) ApplyMove(move *shogi.Move) (*shogi.Position, error) {
+	service.logger.Info("[ApplyMove]", zap.Any("move", move))
+
+	tree, err := service.currentGame()
 	if err != nil {
-		return framework.NewBadRequestError("invalid position", err)
+		return nil, err
+	}
+	pos, _ := service.gameStore.FindPosition(service.engine.GetID())
+
+	board, err := rules.New(pos)
+	if err != nil {
+		return nil, framework.NewInternalServerError("invalid current position", err)
+	}
+	valid, err := board.Validate(move)
+	if err != nil {
+		return nil, framework.NewBadRequestError("illegal move", err)
+	}
+	if err := board.DoMove(valid); err != nil {
+		return nil, framework.NewInternalServerError("do move", err)
+	}
+
+	// the move becomes a variation if the current node has the next move,
+	// or the existing node is selected if it is the same move.
+	n, err := tree.Add(tree.Current, valid)
+	if err != nil {
+		return nil, framework.NewInternalServerError("add move", err)
+	}
+	if err := tree.Select(n.ID); err != nil {
+		return nil, framework.NewInternalServerError("select move", err)
+	}
+
+	next := board.Position()
+	if err := service.setGame(tree, next); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+func (service *engineControlService) Undo() (*shogi.Position, error) {
+	service.logger.Info("[Undo]")
+	tree, err := service.currentGame()
+	if err != nil {
+		return nil, err
+	}
+	path := tree.Path(tree.Current)
+	if len(path) == 1 {
+		return nil, framework.NewBadRequestError("there are no moves to undo", nil)
+	}
+	return service.selectNode(tree, path[len(path)-2].ID)
+}
+
+// Redo moves to the child of the current node last visited, which is the
+// move undone last, or to the main line if none. See game.Node.Next.
+func (service *engineControlService) Redo() (*shogi.Position, error) {
+	service.logger.Info("[Redo]")
+	tree, err := service.currentGame()
+	if err != nil {
+		return nil, err
+	}
+	next := tree.CurrentNode().Next()
+	if next == nil {
+		return nil, framework.NewBadRequestError("there are no moves to redo", nil)
+	}
+	return service.selectNode(tree, next.ID)
+}
+
+// Jump moves to the ply of the line of the current node.
+// See game.Tree.Line about the line.
+func (service *engineControlService) Jump(ply int) (*shogi.Position, error) {
+	service.logger.Info("[Jump]", zap.Int("ply", ply))
+	tree, err := service.currentGame()
+	if err != nil {
+		return nil, err
+	}
+	line := tree.Line()
+	if ply < 0 || ply >= len(line) {
+		return nil, framework.NewBadRequestError(
+			fmt.Sprintf("ply is out of range. ply=%d, moves=%d", ply, len(line)-1),
+			nil,
+		)
+	}
+	return service.selectNode(tree, line[ply].ID)
+}
+
+func (service *engineControlService) SelectNode(id game.NodeID) (*shogi.Position, error) {
+	service.logger.Info("[SelectNode]", zap.Int("node", int(id)))
+	tree, err := service.currentGame()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tree.Find(id); !ok {
+		return nil, nodeError(id, game.ErrNodeNotFound)
+	}
+	return service.selectNode(tree, id)
+}
+
+// DeleteNode deletes the node and its descendants. If the current node is
+// deleted, the parent of the deleted node is selected, and its position
+// is returned. Otherwise the position is nil.
+func (service *engineControlService) DeleteNode(id game.NodeID) (*game.Tree, *shogi.Position, error) {
+	service.logger.Info("[DeleteNode]", zap.Int("node", int(id)))
+	tree, err := service.currentGame()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := tree.Current
+	if err := tree.Delete(id); err != nil {
+		return nil, nil, nodeError(id, err)
+	}
+	if tree.Current == current {
+		// delete it from the stored tree, whose current node may be evaluated meanwhile
+		tree, err := service.updateGame(func(tree *game.Tree) error {
+			return nodeError(id, tree.Delete(id))
+		})
+		return tree, nil, err
+	}
+
+	pos, err := service.selectNode(tree, tree.Current)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree, pos, nil
+}
+
+// PromoteNode makes the node the main line of its siblings.
+func (service *engineControlService) PromoteNode(id game.NodeID) (*game.Tree, error) {
+	service.logger.Info("[PromoteNode]", zap.Int("node", int(id)))
+	return service.updateGame(func(tree *game.Tree) error {
+		return nodeError(id, tree.Promote(id))
+	})
+}
+
+func (service *engineControlService) CommentNode(id game.NodeID, comment string) (*game.Tree, error) {
+	service.logger.Info("[CommentNode]", zap.Int("node", int(id)), zap.String("comment", comment))
+	return service.updateGame(func(tree *game.Tree) error {
+		n, ok := tree.Find(id)
+		if !ok {
+			return nodeError(id, game.ErrNodeNotFound)
+		}
+		n.Comment = comment
+		return nil
+	})
+}
+
+// currentGame returns the game tree of the current position. Returns
+// BAD_REQUEST error if the engine has crashed, or the position has not been set.
+func (service *engineControlService) currentGame() (*game.Tree, error) {
+	if service.engine.GetState() == engine.Crashed {
+		return nil, framework.NewBadRequestError("engine has crashed", nil)
+	}
+	id := service.engine.GetID()
+	tree, ok := service.gameStore.FindGame(id)
+	if !ok {
+		return nil, framework.NewBadRequestError("position is not set. id="+id.String(), nil)
+	}
+	return tree, nil
+}
+
+// updateGame updates the stored game tree without changing the current
+// position. The evaluations set by the engine while updating are kept.
+func (service *engineControlService) updateGame(update func(*game.Tree) error) (*game.Tree, error) {
+	if service.engine.GetState() == engine.Crashed {
+		return nil, framework.NewBadRequestError("engine has crashed", nil)
+	}
+	id := service.engine.GetID()
+	tree, ok, err := service.gameStore.UpdateTree(id, update)
+	if !ok {
+		return nil, framework.NewBadRequestError("position is not set. id="+id.String(), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// selectNode replays the moves from the initial position to the node,
+// and sets the position to the engine.
+func (service *engineControlService) selectNode(tree *game.Tree, id game.NodeID) (*shogi.Position, error) {
+	moves, err := tree.Moves(id)
+	if err != nil {
+		return nil, nodeError(id, err)
+	}
+
+	board, err := rules.New(tree.Initial)
+	if err != nil {
+		return nil, framework.NewInternalServerError("invalid initial position", err)
+	}
+	for _, m := range moves {
+		if err := board.DoMove(m); err != nil {
+			return nil, framework.NewInternalServerError("replay moves", err)
+		}
+	}
+
+	if err := tree.Select(id); err != nil {
+		return nil, nodeError(id, err)
+	}
+	pos := board.Position()
+	if err := service.setGame(tree, pos); err != nil {
+		return nil, err
+	}
+	return pos, nil
+}
+
+// setGame sets the position of the current node to the engine,
+// and stores them.
+func (service *engineControlService) setGame(tree *game.Tree, pos *shogi.Position) error {
+	b, err := positionCommand(tree)
+	if err != nil {
+		return framework.NewInternalServerError("convert game", err)
 	}
+	sfen, err := convert.SFEN(pos)
+	if err != nil {
+		return framework.NewInternalServerError("convert position", err)
+	}
+	return service.setPosition(b, func(id engine.ID) {
+		service.gameStore.UpdateGame(id, tree, pos, sfen)
+	})
+}
+
+// positionCommand returns usi-position command of the current node.
+// The moves are sent with the initial position,
+// so that the engine knows the history for repetitions.
+func positionCommand(tree *game.Tree) ([]byte, error) {
+	sfen, err := convert.SFEN(tree.Initial)
+	if err != nil {
+		return nil, err
+	}
+	moves, err := tree.Moves(tree.Current)
+	if err != nil {
+		return nil, err
+	}
+	usiMoves := make([]string, len(moves))
+	for i, m := range moves {
+		if usiMoves[i], err = convert.Move(m); err != nil {
+			return nil, err
+		}
+	}
+	return convert.PositionWithMoves(sfen, usiMoves), nil
+}
+
+// nodeError converts the error of game.Tree to NOT_FOUND or BAD_REQUEST.
+func nodeError(id game.NodeID, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, game.ErrNodeNotFound):
+		return framework.NewNotFoundError(fmt.Sprintf("node not found. node=%d", id), err)
+	default:
+		return framework.NewBadRequestError(fmt.Sprintf("invalid node. node=%d", id), err)
+	}
+}
+
+// setPosition writes the usi-position command, and calls store to keep
+// the new position. The search is restarted if the engine was thinking.
+func (service *engineControlService) setPosition(b []byte, store func(engine.ID)) error {
+	// the outputs of the old search are handled after the new search starts,
+	// so that they are never regarded as of the new position
+	service.searchMu.Lock()
+	defer service.searchMu.Unlock()
+
+	isThinking := service.engine.GetState() == engine.Thinking
+
+	// stop thinking first
+	if err := service.stop(); err != nil {
+		return err
+	}
+
 	if err := service.write(b); err != nil {
 		return framework.NewInternalServerError("write "+string(b), err)
 	}
 
-	// restart thinking
-	if isThinking {
-		return service.Start()
+	// results of the previous position are no longer valid
+	id := service.engine.GetID()
+	store(id)
+	service.engineInfoStore.DeleteAll(id)
+
+	// restart thinking with the same limits.
+	// mate search is not restarted, because it is for the old position.
+	if isThinking && !service.engine.IsMating() {
+		return service.start(service.engine.GetSearchLimit())
+	}
+	return nil
+}
+
+// checkAlive returns error if the engine is not connected or has crashed.
+func (service *engineControlService) checkAlive() error {
+	switch service.engine.GetState() {
+	case engine.NotConnected:
+		return framework.NewBadRequestError("must initialize engine first", nil)
+	case engine.Crashed:
+		return framework.NewBadRequestError("engine has crashed", nil)
+	default:
+		return nil
+	}
+}
+
+// crashed records the unexpected exit of the engine process.
+func (service *engineControlService) crashed(exitCode int, stderr []string) {
+	egn := service.engine
+	service.logger.Error(
+		"[EngineCrashed]",
+		zap.String("engine id", egn.GetID().String()),
+		zap.Int("exit code", exitCode),
+		zap.Strings("stderr", stderr),
+	)
+
+	service.searchMu.Lock()
+	defer service.searchMu.Unlock()
+	egn.SetCrash(&engine.Crash{ExitCode: exitCode, Stderr: stderr, At: time.Now()})
+	egn.ClearPendingStops()
+	service.setState(engine.Crashed)
+}
+
+// restore connects to the restarted engine process, and restores
+// the options, the current position and the search.
+func (service *engineControlService) restore(options *engine.Options, thinking bool) error {
+	egn := service.engine
+
+	if err := service.Connect(); err != nil {
+		return err
+	}
+
+	// buttons are not restored, because they are actions
+	for _, opt := range options.Checks {
+		if err := service.UpdateCheckOption(opt); err != nil {
+			return err
+		}
+	}
+	for _, opt := range options.Ranges {
+		if err := service.UpdateRangeOption(opt); err != nil {
+			return err
+		}
+	}
+	for _, opt := range options.Selects {
+		if err := service.UpdateSelectOption(opt); err != nil {
+			return err
+		}
+	}
+	for _, opt := range options.Texts {
+		if err := service.UpdateTextOption(opt); err != nil {
+			return err
+		}
+	}
+
+	if tree, ok := service.gameStore.FindGame(egn.GetID()); ok {
+		b, err := positionCommand(tree)
+		if err != nil {
+			return framework.NewInternalServerError("convert game", err)
+		}
+		if err := service.write(b); err != nil {
+			return err
+		}
+	}
+
+	if thinking && !egn.IsMating() {
+		return service.Start(egn.GetSearchLimit())
 	}
 	return nil
 }
 
+// setState updates the engine state and notifies it to subscribers.
+func (service *engineControlService) setState(state engine.State) {
+	egn := service.engine
+	if egn.GetState() == engine.Thinking || state == engine.Thinking {
+		egn.Think(time.Now())
+	}
+	egn.SetState(state)
+	service.publisher.Publish(event.NewState(egn.GetID(), state))
+}
+
+// record records the engine output to the transcript.
+// The sent lines are recorded before written in write, so that
+// the transcript keeps the order the engine has seen.
+func (service *engineControlService) record(b []byte) {
+	service.transcriptStore.Append(service.engine.GetID(), &usi.TranscriptLine{
+		Direction: usi.Received,
+		Text:      string(b),
+		At:        time.Now(),
+	})
+}
+
 func (service *engineControlService) write(bytes []byte) error {
 	service.logger.Info("[Write]", zap.ByteString("message", bytes))
+	service.transcriptStore.Append(service.engine.GetID(), &usi.TranscriptLine{
+		Direction: usi.Sent,
+		Text:      string(bytes),
+		At:        time.Now(),
+	})
 	w := service.connector.Writer()
 	if _, err := w.Write(append(bytes, '\n')); err != nil {
 		return framework.NewInternalServerError("write", err)
 	}
 	return nil
 }
-
-func (service *engineControlService) timeout(ch chan struct{}, timeout time.Duration) error {
-	select {
-	case <-ch:
-		return nil
-	case <-time.After(timeout):
-		return framework.NewInternalServerError("timeout", nil)
-	}
-}
@@ -0,0 +1,1137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/murosan/shogi-board-server/app/domain/config"
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/event"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+	"github.com/murosan/shogi-board-server/app/domain/infrastructure"
+	"github.com/murosan/shogi-board-server/app/domain/infrastructure/fake"
+	"github.com/murosan/shogi-board-server/app/domain/infrastructure/store"
+)
+
+const testEngineID = engine.ID("fake")
+
+func testScript() *fake.Script {
+	return &fake.Script{
+		Name:   "fake engine",
+		Author: "murosan",
+		Options: []string{
+			"name USI_Hash type spin default 256 min 1 max 4096",
+			"name USI_Ponder type check default false",
+		},
+		Infos: []string{
+			"depth 1 score cp 10 pv 7g7f",
+			"depth 2 score cp 20 pv 7g7f 3c3d",
+		},
+		BestMove:  "7g7f",
+		Checkmate: "nomate",
+	}
+}
+
+// testEngines holds the fake engine processes started by the service.
+type testEngines struct {
+	sync.Mutex
+	cmds []*fake.Cmd
+}
+
+func (e *testEngines) last() *fake.Cmd {
+	e.Lock()
+	defer e.Unlock()
+	return e.cmds[len(e.cmds)-1]
+}
+
+func (e *testEngines) count() int {
+	e.Lock()
+	defer e.Unlock()
+	return len(e.cmds)
+}
+
+func newTestService(script *fake.Script, restart config.Restart) (EngineService, *testEngines) {
+	engines := &testEngines{}
+	newCmd := func(string) infrastructure.Cmd {
+		cmd := fake.NewCmd(script)
+		engines.Lock()
+		engines.cmds = append(engines.cmds, cmd)
+		engines.Unlock()
+		return cmd
+	}
+	return newServiceWithCmd(newCmd, restart), engines
+}
+
+// newServiceWithCmd returns new EngineService which starts the engine by newCmd.
+func newServiceWithCmd(newCmd func(string) infrastructure.Cmd, restart config.Restart) EngineService {
+	conf := &config.Config{
+		App: config.App{
+			Engines: map[string]string{testEngineID.String(): "/path/to/fake"},
+			Restart: restart,
+		},
+	}
+
+	return NewEngineService(
+		store.NewEngineStore(),
+		store.NewEngineInfoStore(),
+		store.NewGameStore(),
+		store.NewTranscriptStore(),
+		infrastructure.NewPublisher(),
+		conf,
+		zap.NewNop(),
+		newCmd,
+		infrastructure.NewConnector,
+	)
+}
+
+// eventually fails the test if the condition does not become true in a second.
+func eventually(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("[EngineService] timeout: %s", msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// received returns true if the fake engine has received the command.
+func received(cmd *fake.Cmd, command string) bool {
+	for _, r := range cmd.Received() {
+		if r == command {
+			return true
+		}
+	}
+	return false
+}
+
+func stateOf(t *testing.T, es EngineService) engine.State {
+	t.Helper()
+	status, err := es.GetStatus(testEngineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status.State
+}
+
+func initialPosition() *shogi.Position {
+	return &shogi.Position{
+		Pos: [][]int{
+			{-2, -3, -4, -5, -8, -5, -4, -3, -2},
+			{0, -7, 0, 0, 0, 0, 0, -6, 0},
+			{-1, -1, -1, -1, -1, -1, -1, -1, -1},
+			{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{1, 1, 1, 1, 1, 1, 1, 1, 1},
+			{0, 6, 0, 0, 0, 0, 0, 7, 0},
+			{2, 3, 4, 5, 8, 5, 4, 3, 2},
+		},
+		Cap0:      []int{0, 0, 0, 0, 0, 0, 0},
+		Cap1:      []int{0, 0, 0, 0, 0, 0, 0},
+		Turn:      shogi.Sente,
+		MoveCount: 1,
+	}
+}
+
+func TestEngineService_Connect(t *testing.T) {
+	es, _ := newTestService(testScript(), config.Restart{})
+
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	status, err := es.GetStatus(testEngineID)
+	if err != nil || status.Name != "fake engine" || status.State != engine.Connected {
+		t.Errorf("[EngineService.Connect] unexpected status. status=%v, err=%v", status, err)
+	}
+
+	options, err := es.GetOptions(testEngineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := options.GetRange("USI_Hash"); !ok {
+		t.Errorf("[EngineService.Connect] USI_Hash not found. options=%v", options)
+	}
+	if _, ok := options.GetCheck("USI_Ponder"); !ok {
+		t.Errorf("[EngineService.Connect] USI_Ponder not found. options=%v", options)
+	}
+
+	if err := es.Connect(testEngineID); err == nil {
+		t.Error("[EngineService.Connect] expected error on connecting twice, but got nil")
+	}
+}
+
+func TestEngineService_Connect_Hang(t *testing.T) {
+	defer func(d time.Duration) { readyTimeout = d }(readyTimeout)
+	readyTimeout = 100 * time.Millisecond
+
+	script := testScript()
+	script.HangOn = "isready"
+	script.Stderr = []string{"eval file not found"}
+	es, _ := newTestService(script, config.Restart{})
+
+	err := es.Connect(testEngineID)
+	if err == nil || !strings.Contains(err.Error(), "eval file not found") {
+		t.Errorf("[EngineService.Connect] expected error with stderr, but got %v", err)
+	}
+}
+
+func TestEngineService_StartStop(t *testing.T) {
+	es, engines := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	if err := es.Start(testEngineID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := stateOf(t, es); s != engine.Thinking {
+		t.Errorf("[EngineService.Start] expected thinking, but got %s", s)
+	}
+
+	eventually(t, "infos are stored", func() bool {
+		for _, info := range es.GetResult(testEngineID) {
+			return info.Values["depth"] == 2
+		}
+		return false
+	})
+
+	bm, err := es.StopAndWait(testEngineID, time.Second)
+	if err != nil || bm.Move == nil || bm.Move.Dest.Column != 6 || bm.Move.Dest.Row != 5 {
+		t.Errorf("[EngineService.StopAndWait] unexpected bestmove. bestmove=%v, err=%v", bm, err)
+	}
+	if s := stateOf(t, es); s != engine.StandBy {
+		t.Errorf("[EngineService.StopAndWait] expected standBy, but got %s", s)
+	}
+
+	cmd := engines.last()
+	for _, c := range []string{"usinewgame", "go infinite", "stop"} {
+		if !received(cmd, c) {
+			t.Errorf("[EngineService.StartStop] %s was not sent. received=%v", c, cmd.Received())
+		}
+	}
+}
+
+func TestEngineService_OutputWhileBusy(t *testing.T) {
+	script := testScript()
+	script.Infos = nil
+	for d := 1; d <= 100; d++ {
+		script.Infos = append(script.Infos, fmt.Sprintf("depth %d score cp %d pv 7g7f", d, d))
+	}
+
+	es, _ := newTestService(script, config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	if err := es.Start(testEngineID, &usi.SearchLimit{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// keep the actor busy, like a command waiting for the engine
+	release := make(chan struct{})
+	busy := make(chan struct{})
+	go func() {
+		_ = es.(*engineService).withControl(testEngineID, func(EngineControlService) error {
+			close(busy)
+			<-release
+			return nil
+		})
+	}()
+	<-busy
+	defer close(release)
+
+	// the outputs are still handled
+	_, rev := es.WaitResult(context.Background(), testEngineID, func(uint64) bool { return false }, 0)
+	same := func(r uint64) bool { return r == rev }
+	if _, r := es.WaitResult(context.Background(), testEngineID, same, time.Second); r == rev {
+		t.Error("[EngineService.OutputWhileBusy] outputs were not handled while the actor was busy")
+	}
+}
+
+func TestEngineService_Start_Finite(t *testing.T) {
+	es, _ := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	if err := es.Start(testEngineID, &usi.SearchLimit{Depth: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, "the search finishes", func() bool {
+		_, ok := es.GetBestMove(testEngineID)
+		return ok && stateOf(t, es) == engine.StandBy
+	})
+}
+
+func TestEngineService_Mate(t *testing.T) {
+	es, _ := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	cm, err := es.Mate(testEngineID, 0, time.Second)
+	if err != nil || cm.Status != usi.NoMate {
+		t.Errorf("[EngineService.Mate] unexpected checkmate. checkmate=%v, err=%v", cm, err)
+	}
+}
+
+func TestEngineService_UpdateOption(t *testing.T) {
+	es, engines := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	if err := es.UpdateCheckOption(testEngineID, &engine.Check{Name: "USI_Ponder", Value: true}); err != nil {
+		t.Fatal(err)
+	}
+	rng := &engine.Range{Name: "USI_Hash", Value: 10000, Default: 256, Min: 1, Max: 4096}
+	if err := es.UpdateRangeOption(testEngineID, rng); err == nil {
+		t.Error("[EngineService.UpdateRangeOption] expected error on invalid value, but got nil")
+	}
+
+	cmd := engines.last()
+	eventually(t, "setoption is sent", func() bool {
+		return received(cmd, "setoption name USI_Ponder value true")
+	})
+
+	options, _ := es.GetOptions(testEngineID)
+	if c, ok := options.GetCheck("USI_Ponder"); !ok || !c.Value {
+		t.Errorf("[EngineService.UpdateCheckOption] the value is not kept. option=%v", c)
+	}
+}
+
+func TestEngineService_UpdatePosition(t *testing.T) {
+	es, engines := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	if err := es.Start(testEngineID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := es.UpdatePosition(testEngineID, initialPosition()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1"
+	cmd := engines.last()
+	eventually(t, "the search restarts on the new position", func() bool {
+		r := cmd.Received()
+		return len(r) >= 3 &&
+			r[len(r)-3] == "stop" &&
+			r[len(r)-2] == want &&
+			r[len(r)-1] == "go infinite"
+	})
+
+	if s := stateOf(t, es); s != engine.Thinking {
+		t.Errorf("[EngineService.UpdatePosition] expected thinking, but got %s", s)
+	}
+	if _, ok := es.GetCurrentPosition(testEngineID); !ok {
+		t.Error("[EngineService.UpdatePosition] the position is not stored")
+	}
+}
+
+func TestEngineService_PositionTag(t *testing.T) {
+	script := testScript()
+	script.InfoInterval = 1
+	script.Infos = nil
+	for d := 1; d <= 100; d++ {
+		script.Infos = append(script.Infos, fmt.Sprintf("depth %d score cp %d pv 7g7f", d, d))
+	}
+
+	es, _ := newTestService(script, config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	events, unsubscribe, err := es.Subscribe(testEngineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	// next returns the next event of the type
+	next := func(typ event.Type) *event.Event {
+		t.Helper()
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case e := <-events:
+				if e.Type == typ {
+					return e
+				}
+			case <-timeout:
+				t.Fatalf("[EngineService.PositionTag] timeout waiting for %s", typ)
+			}
+		}
+	}
+
+	if err := es.UpdatePosition(testEngineID, initialPosition()); err != nil {
+		t.Fatal(err)
+	}
+	if err := es.Start(testEngineID, &usi.SearchLimit{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// change the position in the middle of the search
+	var old *usi.PositionTag
+	for e := next(event.Info); e.Info.Values["depth"] < 5; e = next(event.Info) {
+		old = e.Info.Position
+	}
+	if old == nil {
+		t.Fatal("[EngineService.PositionTag] info is not tagged with the position")
+	}
+	// the engine keeps outputting infos of the old search while the move is applied
+	move := &shogi.Move{Source: &shogi.Point{Row: 6, Column: 6}, Dest: &shogi.Point{Row: 5, Column: 6}}
+	_, err = es.(*engineService).changePosition(testEngineID, func(ecs EngineControlService) (*shogi.Position, error) {
+		time.Sleep(20 * time.Millisecond)
+		return ecs.ApplyMove(move)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next(event.Position)
+
+	// infos of the old search are dropped, and the new search starts from depth 1
+	for depth := 1; depth <= 5; depth++ {
+		e := next(event.Info)
+		if e.Info.Position == nil || e.Info.Position.Revision != old.Revision+1 || e.Info.Values["depth"] != depth {
+			t.Fatalf(`
+[app > domain > service > EngineService.PositionTag]
+Expected: depth %d of revision %d
+Actual:   depth %d of %v
+`, depth, old.Revision+1, e.Info.Values["depth"], e.Info.Position)
+		}
+	}
+
+	for mpv, info := range es.GetResult(testEngineID) {
+		if info.Position == nil || info.Position.Revision != old.Revision+1 {
+			t.Errorf("[EngineService.PositionTag] info of the old position is left. multipv=%d, position=%v", mpv, info.Position)
+		}
+	}
+}
+
+func TestEngineService_ApplyMove(t *testing.T) {
+	es, engines := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	move := &shogi.Move{Source: &shogi.Point{Row: 6, Column: 6}, Dest: &shogi.Point{Row: 5, Column: 6}}
+	if _, err := es.ApplyMove(testEngineID, move); err == nil {
+		t.Error("[EngineService.ApplyMove] expected error before setting position")
+	}
+
+	if err := es.UpdatePosition(testEngineID, initialPosition()); err != nil {
+		t.Fatal(err)
+	}
+	if err := es.Start(testEngineID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := es.ApplyMove(testEngineID, move)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Turn != shogi.Gote || pos.MoveCount != 2 || pos.Pos[6][2] != 0 || pos.Pos[5][2] != 1 {
+		t.Errorf("[EngineService.ApplyMove] unexpected position. pos=%v", pos)
+	}
+	if stored, _ := es.GetCurrentPosition(testEngineID); stored != pos {
+		t.Error("[EngineService.ApplyMove] the position is not stored")
+	}
+
+	want := "position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1 moves 7g7f"
+	cmd := engines.last()
+	eventually(t, "the search restarts on the new position", func() bool {
+		r := cmd.Received()
+		return len(r) >= 3 &&
+			r[len(r)-3] == "stop" &&
+			r[len(r)-2] == want &&
+			r[len(r)-1] == "go infinite"
+	})
+
+	// the same move is illegal for the second player
+	if _, err := es.ApplyMove(testEngineID, move); err == nil {
+		t.Error("[EngineService.ApplyMove] expected error for the illegal move")
+	}
+	if stored, _ := es.GetCurrentPosition(testEngineID); stored != pos {
+		t.Error("[EngineService.ApplyMove] the position is changed by the illegal move")
+	}
+}
+
+func TestEngineService_History(t *testing.T) {
+	es, engines := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	if _, err := es.Undo(testEngineID); err == nil {
+		t.Error("[EngineService.Undo] expected error before setting position")
+	}
+	if err := es.UpdatePosition(testEngineID, initialPosition()); err != nil {
+		t.Fatal(err)
+	}
+
+	move := func(sr, sc, dr, dc int) *shogi.Move {
+		return &shogi.Move{Source: &shogi.Point{Row: sr, Column: sc}, Dest: &shogi.Point{Row: dr, Column: dc}}
+	}
+	const sfen = "position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1"
+	cmd := engines.last()
+
+	cases := []struct {
+		name   string
+		do     func() (*shogi.Position, error)
+		err    bool
+		sent   string
+		line   int
+		ply    int
+		moveCt int
+	}{
+		{"7g7f", func() (*shogi.Position, error) { return es.ApplyMove(testEngineID, move(6, 6, 5, 6)) }, false, sfen + " moves 7g7f", 1, 1, 2},
+		{"3c3d", func() (*shogi.Position, error) { return es.ApplyMove(testEngineID, move(2, 2, 3, 2)) }, false, sfen + " moves 7g7f 3c3d", 2, 2, 3},
+		{"redo at last", func() (*shogi.Position, error) { return es.Redo(testEngineID) }, true, "", 2, 2, 3},
+		{"undo", func() (*shogi.Position, error) { return es.Undo(testEngineID) }, false, sfen + " moves 7g7f", 2, 1, 2},
+		{"redo", func() (*shogi.Position, error) { return es.Redo(testEngineID) }, false, sfen + " moves 7g7f 3c3d", 2, 2, 3},
+		{"jump to 0", func() (*shogi.Position, error) { return es.Jump(testEngineID, 0) }, false, sfen, 2, 0, 1},
+		{"jump out of range", func() (*shogi.Position, error) { return es.Jump(testEngineID, 3) }, true, "", 2, 0, 1},
+		{"undo at first", func() (*shogi.Position, error) { return es.Undo(testEngineID) }, true, "", 2, 0, 1},
+		// the same move as the next one keeps the moves to redo
+		{"7g7f again", func() (*shogi.Position, error) { return es.ApplyMove(testEngineID, move(6, 6, 5, 6)) }, false, sfen + " moves 7g7f", 2, 1, 2},
+		// another move becomes a variation
+		{"8c8d", func() (*shogi.Position, error) { return es.ApplyMove(testEngineID, move(2, 7, 3, 7)) }, false, sfen + " moves 7g7f 8c8d", 2, 2, 3},
+		// redo goes back to the variation just undone, not to the main line
+		{"undo variation", func() (*shogi.Position, error) { return es.Undo(testEngineID) }, false, sfen + " moves 7g7f", 2, 1, 2},
+		{"redo variation", func() (*shogi.Position, error) { return es.Redo(testEngineID) }, false, sfen + " moves 7g7f 8c8d", 2, 2, 3},
+		// the visited line is followed from the root
+		{"jump to 0 again", func() (*shogi.Position, error) { return es.Jump(testEngineID, 0) }, false, sfen, 2, 0, 1},
+		{"redo visited line", func() (*shogi.Position, error) { return es.Redo(testEngineID) }, false, sfen + " moves 7g7f", 2, 1, 2},
+		{"jump to 2", func() (*shogi.Position, error) { return es.Jump(testEngineID, 2) }, false, sfen + " moves 7g7f 8c8d", 2, 2, 3},
+		// selecting a node changes the visited line
+		{"select 3c3d", func() (*shogi.Position, error) { return es.SelectNode(testEngineID, 2) }, false, sfen + " moves 7g7f 3c3d", 2, 2, 3},
+		{"back to 0", func() (*shogi.Position, error) { return es.Jump(testEngineID, 0) }, false, sfen, 2, 0, 1},
+		{"2g2f", func() (*shogi.Position, error) { return es.ApplyMove(testEngineID, move(6, 1, 5, 1)) }, false, sfen + " moves 2g2f", 1, 1, 2},
+	}
+
+	for i, c := range cases {
+		pos, err := c.do()
+		if (err != nil) != c.err {
+			t.Fatalf("[EngineService.History] %s: Index: %d, unexpected error: %v", c.name, i, err)
+		}
+		if err == nil {
+			eventually(t, c.name+": "+c.sent, func() bool {
+				r := cmd.Received()
+				return r[len(r)-1] == c.sent
+			})
+			if pos.MoveCount != c.moveCt {
+				t.Errorf("[EngineService.History] %s: expected move count %d, but got %d", c.name, c.moveCt, pos.MoveCount)
+			}
+		}
+
+		tree, _ := es.GetGame(testEngineID)
+		line, ply := len(tree.Line())-1, len(tree.Path(tree.Current))-1
+		if line != c.line || ply != c.ply {
+			t.Errorf("[EngineService.History] %s: expected %d moves at ply %d, but got %d at %d",
+				c.name, c.line, c.ply, line, ply)
+		}
+	}
+}
+
+func TestEngineService_SetGame(t *testing.T) {
+	es, engines := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	move := func(sr, sc, dr, dc int) *shogi.Move {
+		return &shogi.Move{Source: &shogi.Point{Row: sr, Column: sc}, Dest: &shogi.Point{Row: dr, Column: dc}}
+	}
+	const sfen = "position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1"
+	cmd := engines.last()
+
+	pos, err := es.SetGame(testEngineID, initialPosition(), []*shogi.Move{move(6, 6, 5, 6), move(2, 2, 3, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Turn != shogi.Sente || pos.MoveCount != 3 {
+		t.Errorf("[EngineService.SetGame] unexpected position: %v", pos)
+	}
+	eventually(t, "moves 7g7f 3c3d", func() bool {
+		r := cmd.Received()
+		return r[len(r)-1] == sfen+" moves 7g7f 3c3d"
+	})
+
+	tree, _ := es.GetGame(testEngineID)
+	if line, ply := len(tree.Line())-1, len(tree.Path(tree.Current))-1; line != 2 || ply != 2 {
+		t.Errorf("[EngineService.SetGame] expected 2 moves at ply 2, but got %d at %d", line, ply)
+	}
+
+	// the illegal move keeps the current game
+	if _, err := es.SetGame(testEngineID, initialPosition(), []*shogi.Move{move(6, 6, 5, 6), move(6, 6, 5, 6)}); err == nil {
+		t.Error("[EngineService.SetGame] expected error for the illegal move")
+	}
+	invalid := initialPosition()
+	invalid.Turn = 0
+	if _, err := es.SetGame(testEngineID, invalid, nil); err == nil {
+		t.Error("[EngineService.SetGame] expected error for the invalid position")
+	}
+	if tree, _ := es.GetGame(testEngineID); len(tree.Line()) != 3 {
+		t.Errorf("[EngineService.SetGame] game was changed by the failed request: %v", tree.Line())
+	}
+}
+
+func TestEngineService_GameTree(t *testing.T) {
+	es, engines := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	if err := es.UpdatePosition(testEngineID, initialPosition()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 0 - 1 (7g7f)
+	//   \ 2 (2g2f)
+	steps := []func() error{
+		func() error {
+			_, err := es.ApplyMove(testEngineID, &shogi.Move{Source: &shogi.Point{Row: 6, Column: 6}, Dest: &shogi.Point{Row: 5, Column: 6}})
+			return err
+		},
+		func() error { _, err := es.Undo(testEngineID); return err },
+		func() error {
+			_, err := es.ApplyMove(testEngineID, &shogi.Move{Source: &shogi.Point{Row: 6, Column: 1}, Dest: &shogi.Point{Row: 5, Column: 1}})
+			return err
+		},
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree, err := es.PromoteNode(testEngineID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := tree.Root.Children; len(c) != 2 || c[0].ID != 2 || c[1].ID != 1 {
+		t.Errorf("[EngineService.PromoteNode] the node is not promoted. children=%v", c)
+	}
+
+	if tree, err = es.CommentNode(testEngineID, 1, "good move"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := tree.Find(1); n.Comment != "good move" {
+		t.Errorf("[EngineService.CommentNode] the comment is not set. node=%v", n)
+	}
+
+	const sfen = "position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1"
+	cmd := engines.last()
+	if _, err := es.SelectNode(testEngineID, 1); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the engine follows the selected node", func() bool {
+		r := cmd.Received()
+		return r[len(r)-1] == sfen+" moves 7g7f"
+	})
+
+	if _, err := es.SelectNode(testEngineID, 100); err == nil {
+		t.Error("[EngineService.SelectNode] expected error for the unknown node")
+	}
+
+	// deleting the current node moves to the parent
+	if tree, err = es.DeleteNode(testEngineID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tree.Find(1); ok || tree.Current != 0 {
+		t.Errorf("[EngineService.DeleteNode] unexpected tree. current=%d", tree.Current)
+	}
+	eventually(t, "the engine follows the parent", func() bool {
+		r := cmd.Received()
+		return r[len(r)-1] == sfen
+	})
+
+	// the evaluations of the engine are stored to the current node
+	if err := es.Start(testEngineID, nil); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the evaluation is stored", func() bool {
+		tree, _ := es.GetGame(testEngineID)
+		eval := tree.CurrentNode().Evaluation
+		return eval != nil && eval.Depth == 2 && eval.Score == 20
+	})
+}
+
+// slowGameStore takes time after finding a game, so that the engine
+// evaluates the position while the found game is being updated.
+type slowGameStore struct {
+	store.GameStore
+}
+
+func (s *slowGameStore) FindGame(id engine.ID) (*game.Tree, bool) {
+	tree, ok := s.GameStore.FindGame(id)
+	time.Sleep(time.Millisecond)
+	return tree, ok
+}
+
+func TestEngineService_CommentWhileThinking(t *testing.T) {
+	script := testScript()
+	script.InfoInterval = 1
+	script.Infos = nil
+	for d := 1; d <= 100; d++ {
+		script.Infos = append(script.Infos, fmt.Sprintf("depth %d score cp %d pv 7g7f", d, d))
+	}
+	es, _ := newTestService(script, config.Restart{})
+	es.(*engineService).gameStore = &slowGameStore{es.(*engineService).gameStore}
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+	if err := es.UpdatePosition(testEngineID, initialPosition()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := es.ApplyMove(testEngineID, &shogi.Move{
+		Source: &shogi.Point{Row: 6, Column: 6},
+		Dest:   &shogi.Point{Row: 5, Column: 6},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	depth := func() int {
+		tree, _ := es.GetGame(testEngineID)
+		if eval := tree.CurrentNode().Evaluation; eval != nil {
+			return eval.Depth
+		}
+		return 0
+	}
+
+	// comments the nodes while the engine evaluates the current node
+	done := make(chan struct{})
+	commented := make(chan int)
+	go func() {
+		n := 0
+		for {
+			select {
+			case <-done:
+				commented <- n
+				return
+			default:
+			}
+			n++
+			if _, err := es.CommentNode(testEngineID, game.NodeID(n%2), fmt.Sprint(n)); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	if err := es.Start(testEngineID, &usi.SearchLimit{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the evaluation is never lost by the comments
+	last := 0
+	deadline := time.Now().Add(3 * time.Second)
+	for last < 100 && time.Now().Before(deadline) {
+		d := depth()
+		if d < last {
+			t.Fatalf("[EngineService.CommentNode] the evaluation went back from depth %d to %d", last, d)
+		}
+		last = d
+	}
+	close(done)
+	n := <-commented
+
+	if d := depth(); d != 100 {
+		t.Errorf("[EngineService.CommentNode] expected the evaluation of depth 100, but got %d", d)
+	}
+	tree, _ := es.GetGame(testEngineID)
+	if tree.CurrentNode().Comment == "" || tree.Root.Comment == "" || n < 2 {
+		t.Errorf("[EngineService.CommentNode] the comments are not stored. comments=%d", n)
+	}
+}
+
+func TestEngineService_WaitResult(t *testing.T) {
+	es, _ := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	_, rev := es.WaitResult(context.Background(), testEngineID, func(uint64) bool { return false }, time.Minute)
+	same := func(r uint64) bool { return r == rev }
+
+	// the waiting is canceled with the context, e.g. the client has gone
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, r := es.WaitResult(ctx, testEngineID, same, time.Minute); r != rev || time.Since(start) > time.Second {
+		t.Errorf("[EngineService.WaitResult] expected revision %d on cancel, but got %d in %v", rev, r, time.Since(start))
+	}
+
+	// returns when the result changes
+	time.AfterFunc(50*time.Millisecond, func() { _ = es.Start(testEngineID, &usi.SearchLimit{}) })
+	start = time.Now()
+	if _, r := es.WaitResult(context.Background(), testEngineID, same, time.Minute); r == rev || time.Since(start) > time.Second {
+		t.Errorf("[EngineService.WaitResult] expected new revision, but got %d in %v", r, time.Since(start))
+	}
+}
+
+func TestEngineService_Replay(t *testing.T) {
+	// session runs the same controls on the engine, and returns the transcript
+	session := func(es EngineService) []*usi.TranscriptLine {
+		t.Helper()
+		if err := es.Connect(testEngineID); err != nil {
+			t.Fatal(err)
+		}
+		if err := es.UpdatePosition(testEngineID, initialPosition()); err != nil {
+			t.Fatal(err)
+		}
+		if err := es.Start(testEngineID, &usi.SearchLimit{}); err != nil {
+			t.Fatal(err)
+		}
+		eventually(t, "info", func() bool { return len(es.GetResult(testEngineID)) != 0 })
+		if _, err := es.StopAndWait(testEngineID, time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if err := es.Close(testEngineID); err != nil {
+			t.Fatal(err)
+		}
+		return es.GetTranscript(testEngineID)
+	}
+
+	es, _ := newTestService(testScript(), config.Restart{})
+	recorded := session(es)
+
+	var file strings.Builder
+	for _, l := range recorded {
+		file.WriteString(l.String() + "\n")
+	}
+	replayed := session(newServiceWithCmd(func(string) infrastructure.Cmd {
+		cmd, err := infrastructure.NewReplayCmd(strings.NewReader(file.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cmd
+	}, config.Restart{}))
+
+	texts := func(lines []*usi.TranscriptLine) string {
+		var a []string
+		for _, l := range lines {
+			a = append(a, string(l.Direction)+" "+l.Text)
+		}
+		return strings.Join(a, "\n")
+	}
+	if texts(recorded) != texts(replayed) {
+		t.Errorf(`
+[app > domain > service > EngineService.Replay]
+Expected: %s
+Actual:   %s
+`, texts(recorded), texts(replayed))
+	}
+}
+
+func TestEngineService_Close(t *testing.T) {
+	es, engines := newTestService(testScript(), config.Restart{})
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := es.Close(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	if !received(engines.last(), "quit") {
+		t.Errorf("[EngineService.Close] quit was not sent. received=%v", engines.last().Received())
+	}
+	if _, err := es.GetStatus(testEngineID); err == nil {
+		t.Error("[EngineService.Close] the engine still exists")
+	}
+}
+
+func TestEngineService_CloseAll_Hang(t *testing.T) {
+	cases := []struct {
+		hangOn     string
+		ignoreTerm bool
+	}{
+		{"quit", false},
+		{"quit", true},
+		// the process is alive after failing to connect
+		{"isready", true},
+	}
+
+	defer func(d time.Duration) { readyTimeout = d }(readyTimeout)
+	readyTimeout = 50 * time.Millisecond
+
+	for i, c := range cases {
+		script := testScript()
+		script.HangOn = c.hangOn
+		script.IgnoreTerm = c.ignoreTerm
+		es, engines := newTestService(script, config.Restart{})
+		_ = es.Connect(testEngineID)
+
+		if err := es.CloseAll(60 * time.Millisecond); err != nil {
+			t.Errorf("[EngineService.CloseAll] Index: %d, unexpected error: %v", i, err)
+		}
+		if err := engines.last().Wait(time.Second); err == nil {
+			t.Errorf("[EngineService.CloseAll] Index: %d, the engine was not killed", i)
+		}
+		if _, err := es.GetStatus(testEngineID); err == nil {
+			t.Errorf("[EngineService.CloseAll] Index: %d, the engine still exists", i)
+		}
+	}
+}
+
+func TestEngineService_Idle(t *testing.T) {
+	defer func(d time.Duration) { idleCheckInterval = d }(idleCheckInterval)
+	idleCheckInterval = 10 * time.Millisecond
+
+	es, _ := newTestService(testScript(), config.Restart{})
+	defer es.CloseAll(time.Second)
+	es.(*engineService).config.App.Idle = config.Idle{Timeout: 100 * time.Millisecond}
+
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	// subscribing via API would keep the engine alive
+	events, unsubscribe := es.(*engineService).publisher.Subscribe(testEngineID)
+	defer unsubscribe()
+
+	// the thinking engine is not idle
+	if err := es.Start(testEngineID, &usi.SearchLimit{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if s := stateOf(t, es); s != engine.Thinking {
+		t.Fatalf("[EngineService.Idle] expected thinking, but got %v", s)
+	}
+
+	// API access resets the idle time
+	if err := es.Stop(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		time.Sleep(50 * time.Millisecond)
+		es.GetResult(testEngineID)
+	}
+	if _, err := es.GetStatus(testEngineID); err != nil {
+		t.Fatal("[EngineService.Idle] the accessed engine was closed")
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type != event.Closed {
+				continue
+			}
+			if e.Reason != event.ReasonIdle {
+				t.Errorf("[EngineService.Idle] unexpected reason: %s", e.Reason)
+			}
+			if _, err := es.GetStatus(testEngineID); err == nil {
+				t.Error("[EngineService.Idle] the engine still exists")
+			}
+			return
+		case <-timeout:
+			t.Fatal("[EngineService.Idle] timeout waiting for the idle engine to be closed")
+		}
+	}
+}
+
+func TestEngineService_Idle_Watched(t *testing.T) {
+	defer func(d time.Duration) { idleCheckInterval = d }(idleCheckInterval)
+	idleCheckInterval = 10 * time.Millisecond
+
+	es, _ := newTestService(testScript(), config.Restart{})
+	defer es.CloseAll(time.Second)
+	es.(*engineService).config.App.Idle = config.Idle{Timeout: 50 * time.Millisecond}
+
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	store := es.(*engineService).engineStore
+	_, unsubscribe, err := es.Subscribe(testEngineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, unwatch, err := es.WatchResult(testEngineID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the engine with open streams is not idle
+	time.Sleep(200 * time.Millisecond)
+	if !store.Exists(testEngineID) {
+		t.Fatal("[EngineService.Idle] the watched engine was closed")
+	}
+
+	// ending twice releases the stream once
+	unsubscribe()
+	unsubscribe()
+	time.Sleep(200 * time.Millisecond)
+	if !store.Exists(testEngineID) {
+		t.Fatal("[EngineService.Idle] the watched engine was closed")
+	}
+
+	unwatch()
+	eventually(t, "the engine was not closed after the streams ended", func() bool {
+		return !store.Exists(testEngineID)
+	})
+}
+
+func TestEngineService_Health(t *testing.T) {
+	es, engines := newTestService(testScript(), config.Restart{})
+	defer es.CloseAll(time.Second)
+	es.(*engineService).config.App.Health = config.Health{
+		Interval:    10 * time.Millisecond,
+		Timeout:     50 * time.Millisecond,
+		MaxFailures: 2,
+	}
+
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	connected, err := es.GetHealth(testEngineID)
+	if err != nil || !connected.Healthy {
+		t.Fatalf("[EngineService.Health] unexpected health after connect: %v, %v", connected, err)
+	}
+
+	eventually(t, "the engine is checked", func() bool {
+		h, err := es.GetHealth(testEngineID)
+		return err == nil && h.Healthy && h.CheckedAt.After(connected.CheckedAt)
+	})
+
+	// the thinking engine is not checked
+	if err := es.Start(testEngineID, nil); err != nil {
+		t.Fatal(err)
+	}
+	n := len(engines.last().Received())
+	time.Sleep(50 * time.Millisecond)
+	if r := engines.last().Received(); len(r) != n {
+		t.Errorf("[EngineService.Health] unexpected commands while thinking: %v", r[n:])
+	}
+}
+
+func TestEngineService_Health_Unhealthy(t *testing.T) {
+	cases := []struct {
+		restart bool
+	}{
+		{false},
+		{true},
+	}
+
+	for i, c := range cases {
+		script := testScript()
+		script.HangOn = "position"
+		es, engines := newTestService(script, config.Restart{Enabled: true, MaxRestarts: 1, Window: time.Minute})
+		es.(*engineService).config.App.Health = config.Health{
+			Interval:    10 * time.Millisecond,
+			Timeout:     20 * time.Millisecond,
+			MaxFailures: 2,
+			Restart:     c.restart,
+		}
+
+		if err := es.Connect(testEngineID); err != nil {
+			t.Fatal(err)
+		}
+		if err := es.UpdatePosition(testEngineID, initialPosition()); err != nil {
+			t.Fatal(err)
+		}
+
+		if c.restart {
+			// killed and restarted, and then killed again beyond the budget
+			eventually(t, "the unhealthy engine is restarted", func() bool {
+				status, err := es.GetStatus(testEngineID)
+				return err == nil && engines.count() == 2 &&
+					status.Restarts == 1 && status.State == engine.Crashed
+			})
+		} else {
+			eventually(t, "the engine becomes unhealthy", func() bool {
+				h, err := es.GetHealth(testEngineID)
+				return err == nil && !h.Healthy && h.Failures >= 2
+			})
+			if engines.count() != 1 || stateOf(t, es) == engine.Crashed {
+				t.Errorf("[EngineService.Health] Index: %d, the engine was killed", i)
+			}
+		}
+		_ = es.CloseAll(time.Second)
+	}
+}
+
+func TestEngineService_Crash(t *testing.T) {
+	script := testScript()
+	script.CrashOn = "go"
+	script.CrashCode = 3
+	es, engines := newTestService(script, config.Restart{Enabled: true, MaxRestarts: 1, Window: time.Minute})
+
+	if err := es.Connect(testEngineID); err != nil {
+		t.Fatal(err)
+	}
+	defer es.CloseAll(time.Second)
+
+	if err := es.UpdateCheckOption(testEngineID, &engine.Check{Name: "USI_Ponder", Value: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := es.UpdatePosition(testEngineID, initialPosition()); err != nil {
+		t.Fatal(err)
+	}
+
+	// crashes on 'go', restarts and resumes the search,
+	// and then crashes again beyond the budget
+	if err := es.Start(testEngineID, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, "the engine is left crashed", func() bool {
+		status, err := es.GetStatus(testEngineID)
+		return err == nil && engines.count() == 2 &&
+			status.State == engine.Crashed && status.Restarts == 1 &&
+			len(engines.last().Received()) > 0 &&
+			engines.last().Received()[len(engines.last().Received())-1] == "go infinite"
+	})
+
+	status, _ := es.GetStatus(testEngineID)
+	if status.Crash == nil ||
+		status.Crash.ExitCode != 3 ||
+		len(status.Crash.Stderr) == 0 ||
+		status.Crash.Stderr[len(status.Crash.Stderr)-1] != "crashed on go" {
+		t.Errorf("[EngineService.Crash] unexpected crash. crash=%v", status.Crash)
+	}
+
+	cmd := engines.last()
+	for _, c := range []string{
+		"setoption name USI_Ponder value true",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1",
+	} {
+		if !received(cmd, c) {
+			t.Errorf("[EngineService.Crash] %s was not restored. received=%v", c, cmd.Received())
+		}
+	}
+
+	if err := es.Start(testEngineID, nil); err == nil {
+		t.Error("[EngineService.Crash] expected error on starting crashed engine, but got nil")
+	}
+	if err := es.Close(testEngineID); err != nil {
+		t.Errorf("[EngineService.Crash] failed to close crashed engine: %v", err)
+	}
+}
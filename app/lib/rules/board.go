@@ -0,0 +1,193 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rules provides the rules of shogi on shogi.Position,
+// such as move generation, check detection and making moves.
+package rules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+const (
+	rows    = 9
+	columns = 9
+	squares = rows * columns
+
+	// handKinds is the size of the hand arrays, which are indexed by
+	// the kinds from shogi.Fu0 to shogi.Gyoku0. Index 0 is unused.
+	handKinds = int(shogi.Gyoku0) + 1
+
+	// capKinds is the length of shogi.Position.Cap0 and Cap1,
+	// which are the kinds from shogi.Fu0 to shogi.Hisha0.
+	capKinds = int(shogi.Hisha0)
+)
+
+// noSquare is the square of the king which is not on the board.
+const noSquare = -1
+
+// Board is a position of shogi which knows the rules.
+//
+// The squares are indexed by row*9 + column in the same coordinates
+// as shogi.Point, where the column 0 is the first file. Note that
+// the columns of shogi.Position.Pos are in the opposite order.
+type Board struct {
+	cells     [squares]shogi.Piece
+	hands     [2][handKinds]int
+	turn      shogi.Turn
+	moveCount int
+
+	// squares of the kings of each player
+	kings [2]int
+
+	// moves made by DoMove, to be undone
+	history []undo
+}
+
+// move is a move on the Board.
+type move struct {
+	// from is the source square, or noSquare for drops.
+	from int
+	to   int
+
+	// piece is the moved piece before promotion, or the dropped piece.
+	piece   shogi.Piece
+	promote bool
+}
+
+// undo is a record to undo a move.
+type undo struct {
+	move     move
+	captured shogi.Piece
+}
+
+// New returns new Board of the position.
+func New(pos *shogi.Position) (*Board, error) {
+	if pos == nil {
+		return nil, errors.New("position is nil")
+	}
+	if len(pos.Pos) != rows {
+		return nil, errors.New("length of position.Pos is not 9")
+	}
+	if len(pos.Cap0) != capKinds || len(pos.Cap1) != capKinds {
+		return nil, errors.New("length of position.Cap* is not 7")
+	}
+	if pos.Turn != shogi.Sente && pos.Turn != shogi.Gote {
+		return nil, fmt.Errorf("unknown turn number. Turn = %d", pos.Turn)
+	}
+
+	b := &Board{
+		turn:      pos.Turn,
+		moveCount: pos.MoveCount,
+		kings:     [2]int{noSquare, noSquare},
+	}
+
+	for r, row := range pos.Pos {
+		if len(row) != columns {
+			return nil, fmt.Errorf("length of position.Pos[%d] is not 9", r)
+		}
+		for i, id := range row {
+			p := shogi.Piece(id)
+			if p == shogi.Empty {
+				continue
+			}
+			if !isValidKind(kindOf(p)) {
+				return nil, fmt.Errorf("unknown piece. piece = %d", id)
+			}
+
+			sq := r*columns + (columns - 1 - i)
+			b.cells[sq] = p
+
+			if kindOf(p) == shogi.Gyoku0 {
+				s := side(ownerOf(p))
+				if b.kings[s] != noSquare {
+					return nil, errors.New("there are two kings of the same player")
+				}
+				b.kings[s] = sq
+			}
+		}
+	}
+
+	for i := 0; i < capKinds; i++ {
+		if pos.Cap0[i] < 0 || pos.Cap1[i] < 0 {
+			return nil, errors.New("the count of captured pieces is negative")
+		}
+		b.hands[side(shogi.Sente)][i+1] = pos.Cap0[i]
+		b.hands[side(shogi.Gote)][i+1] = pos.Cap1[i]
+	}
+
+	return b, nil
+}
+
+// Position returns the current position of the board.
+func (b *Board) Position() *shogi.Position {
+	pos := &shogi.Position{
+		Pos:       make([][]int, rows),
+		Cap0:      make([]int, capKinds),
+		Cap1:      make([]int, capKinds),
+		Turn:      b.turn,
+		MoveCount: b.moveCount,
+	}
+
+	for r := 0; r < rows; r++ {
+		pos.Pos[r] = make([]int, columns)
+		for c := 0; c < columns; c++ {
+			pos.Pos[r][columns-1-c] = b.cells[r*columns+c].ToInt()
+		}
+	}
+
+	for i := 0; i < capKinds; i++ {
+		pos.Cap0[i] = b.hands[side(shogi.Sente)][i+1]
+		pos.Cap1[i] = b.hands[side(shogi.Gote)][i+1]
+	}
+
+	return pos
+}
+
+// Turn returns the player to move.
+func (b *Board) Turn() shogi.Turn { return b.turn }
+
+// At returns the piece at the point, or shogi.Empty if the point
+// is out of the board.
+func (b *Board) At(p *shogi.Point) shogi.Piece {
+	if p == nil || !inside(p.Row, p.Column) {
+		return shogi.Empty
+	}
+	return b.cells[square(p.Row, p.Column)]
+}
+
+// side returns the index of the player for arrays.
+func side(player shogi.Turn) int {
+	if player == shogi.Sente {
+		return 0
+	}
+	return 1
+}
+
+func square(row, column int) int { return row*columns + column }
+
+func inside(row, column int) bool {
+	return row >= 0 && row < rows && column >= 0 && column < columns
+}
+
+// pointOf returns the shogi.Point of the square.
+func pointOf(sq int) *shogi.Point {
+	if sq == noSquare {
+		return &shogi.Point{Row: -1, Column: -1}
+	}
+	return &shogi.Point{Row: sq / columns, Column: sq % columns}
+}
+
+// toMove converts the move to shogi.Move.
+func (m move) toMove() *shogi.Move {
+	return &shogi.Move{
+		Source:     pointOf(m.from),
+		Dest:       pointOf(m.to),
+		PieceID:    m.piece,
+		IsPromoted: m.promote,
+	}
+}
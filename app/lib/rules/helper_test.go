@@ -0,0 +1,29 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/lib/usi/parse"
+)
+
+const startpos = parse.StartPos
+
+// position parses the SFEN for tests. The input must be valid.
+func position(t *testing.T, sfen string) *shogi.Position {
+	t.Helper()
+	pos, err := parse.SFEN(sfen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pos
+}
+
+func newBoard(t *testing.T, sfen string) *Board {
+	t.Helper()
+	b, err := New(position(t, sfen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+// Errors of illegal moves. Validate and DoMove return them wrapped.
+var (
+	ErrInvalidPoint  = errors.New("the point is out of the board")
+	ErrNoPiece       = errors.New("the player's piece is not at the source")
+	ErrNotInHand     = errors.New("the piece is not in the player's hand")
+	ErrCannotMove    = errors.New("the piece cannot move to the destination")
+	ErrPromotion     = errors.New("the piece cannot promote, or must promote")
+	ErrDeadPiece     = errors.New("the piece would have no moves at the destination")
+	ErrNifu          = errors.New("there is already the player's pawn in the file")
+	ErrLeftInCheck   = errors.New("the king would be left in check")
+	ErrUchifuzume    = errors.New("checkmate by dropping a pawn is not allowed")
+	ErrNothingToUndo = errors.New("there are no moves to undo")
+)
+
+// Validate checks whether the move is legal for the player to move, and
+// returns the move with PieceID filled, which is the moved piece before
+// promotion, or the dropped piece of the player.
+//
+// The PieceID of the given move may be 0 for moves on the board, like the
+// moves parsed from USI. For drops, only the kind of PieceID is used.
+func (b *Board) Validate(m *shogi.Move) (*shogi.Move, error) {
+	mv, err := b.find(m)
+	if err != nil {
+		return nil, err
+	}
+	return mv.toMove(), nil
+}
+
+// DoMove makes the move if it is legal. See Validate.
+func (b *Board) DoMove(m *shogi.Move) error {
+	mv, err := b.find(m)
+	if err != nil {
+		return err
+	}
+	b.doMove(mv)
+	return nil
+}
+
+// UndoMove undoes the last move made by DoMove.
+func (b *Board) UndoMove() error {
+	if len(b.history) == 0 {
+		return ErrNothingToUndo
+	}
+	b.undoMove()
+	return nil
+}
+
+// find returns the legal move which matches the given move,
+// or an error explaining why the move is illegal.
+func (b *Board) find(m *shogi.Move) (move, error) {
+	if m == nil || m.Dest == nil || !inside(m.Dest.Row, m.Dest.Column) {
+		return move{}, fmt.Errorf("invalid destination: %w", ErrInvalidPoint)
+	}
+
+	us := b.turn
+	to := square(m.Dest.Row, m.Dest.Column)
+
+	var mv move
+	if m.Source == nil || (m.Source.Row == -1 && m.Source.Column == -1) {
+		kind := kindOf(m.PieceID)
+		if kind < shogi.Fu0 || kind > shogi.Hisha0 || b.hands[side(us)][kind] == 0 {
+			return move{}, fmt.Errorf("piece = %d: %w", m.PieceID, ErrNotInHand)
+		}
+		if b.cells[to] != shogi.Empty {
+			return move{}, fmt.Errorf("drop to the occupied point: %w", ErrCannotMove)
+		}
+		if isDead(kind, m.Dest.Row, us) {
+			return move{}, ErrDeadPiece
+		}
+		if kind == shogi.Fu0 && b.hasPawnInFile(m.Dest.Column, us) {
+			return move{}, ErrNifu
+		}
+		mv = move{from: noSquare, to: to, piece: pieceOf(kind, us)}
+	} else {
+		if !inside(m.Source.Row, m.Source.Column) {
+			return move{}, fmt.Errorf("invalid source: %w", ErrInvalidPoint)
+		}
+		from := square(m.Source.Row, m.Source.Column)
+		p := b.cells[from]
+		if p == shogi.Empty || ownerOf(p) != us || (m.PieceID != shogi.Empty && m.PieceID != p) {
+			return move{}, ErrNoPiece
+		}
+
+		found, reachable := false, false
+		for _, pm := range b.appendPieceMoves(nil, from) {
+			if pm.to != to {
+				continue
+			}
+			reachable = true
+			if pm.promote == m.IsPromoted {
+				mv, found = pm, true
+			}
+		}
+		if !reachable {
+			return move{}, ErrCannotMove
+		}
+		if !found {
+			return move{}, ErrPromotion
+		}
+	}
+
+	if b.leavesInCheck(mv) {
+		return move{}, ErrLeftInCheck
+	}
+	if b.isUchifuzume(mv) {
+		return move{}, ErrUchifuzume
+	}
+	return mv, nil
+}
+
+// appendPieceMoves appends the pseudo legal moves of the piece at the square.
+func (b *Board) appendPieceMoves(moves []move, from int) []move {
+	// generating all moves is simple, and fast enough for validation
+	for _, m := range b.pseudoLegalMoves(nil) {
+		if m.from == from {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// hasPawnInFile returns true if the player's unpromoted pawn is in the column.
+func (b *Board) hasPawnInFile(column int, player shogi.Turn) bool {
+	fu := pieceOf(shogi.Fu0, player)
+	for r := 0; r < rows; r++ {
+		if b.cells[square(r, column)] == fu {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package rules
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+func point(row, column int) *shogi.Point { return &shogi.Point{Row: row, Column: column} }
+
+var hand = point(-1, -1)
+
+func TestBoard_Validate(t *testing.T) {
+	cases := []struct {
+		sfen string
+		in   *shogi.Move
+		want *shogi.Move
+		err  error
+	}{
+		// 7g7f
+		{
+			startpos,
+			&shogi.Move{Source: point(6, 6), Dest: point(5, 6)},
+			&shogi.Move{Source: point(6, 6), Dest: point(5, 6), PieceID: shogi.Fu0},
+			nil,
+		},
+		// 8h2b+ after 7g7f 3c3d
+		{
+			"lnsgkgsnl/1r5b1/pppppp1pp/6p2/9/2P6/PP1PPPPPP/1B5R1/LNSGKGSNL b - 3",
+			&shogi.Move{Source: point(7, 7), Dest: point(1, 1), IsPromoted: true},
+			&shogi.Move{Source: point(7, 7), Dest: point(1, 1), PieceID: shogi.Kaku0, IsPromoted: true},
+			nil,
+		},
+		// G*5b by the second player, with the piece of the first player like USI
+		{
+			"4k4/9/9/9/9/9/9/9/4K4 w g 1",
+			&shogi.Move{Source: hand, Dest: point(7, 4), PieceID: shogi.Kin0},
+			&shogi.Move{Source: hand, Dest: point(7, 4), PieceID: shogi.Kin1},
+			nil,
+		},
+		{startpos, &shogi.Move{Source: point(6, 6), Dest: point(9, 6)}, nil, ErrInvalidPoint},
+		{startpos, &shogi.Move{Source: point(5, 6), Dest: point(4, 6)}, nil, ErrNoPiece},
+		{startpos, &shogi.Move{Source: point(2, 6), Dest: point(3, 6)}, nil, ErrNoPiece},
+		{startpos, &shogi.Move{Source: point(6, 6), Dest: point(5, 6), PieceID: shogi.Kin0}, nil, ErrNoPiece},
+		{startpos, &shogi.Move{Source: point(6, 6), Dest: point(4, 6)}, nil, ErrCannotMove},
+		{startpos, &shogi.Move{Source: hand, Dest: point(4, 4), PieceID: shogi.Fu0}, nil, ErrNotInHand},
+		// 7g7f+ out of the promotion zone
+		{startpos, &shogi.Move{Source: point(6, 6), Dest: point(5, 6), IsPromoted: true}, nil, ErrPromotion},
+		// 1b1a without promotion
+		{"4k4/8P/9/9/9/9/9/9/4K4 b - 1", &shogi.Move{Source: point(1, 0), Dest: point(0, 0)}, nil, ErrPromotion},
+		{"4k4/9/9/9/9/9/9/9/4K4 b L 1", &shogi.Move{Source: hand, Dest: point(0, 0), PieceID: shogi.Kyou0}, nil, ErrDeadPiece},
+		{"4k4/9/9/9/9/4P4/9/9/4K4 b P 1", &shogi.Move{Source: hand, Dest: point(3, 4), PieceID: shogi.Fu0}, nil, ErrNifu},
+		{"4k4/9/9/9/9/9/9/9/4K3r b - 1", &shogi.Move{Source: point(8, 4), Dest: point(8, 3)}, nil, ErrLeftInCheck},
+		{"8k/9/6NG1/9/9/9/9/9/4K4 b P 1", &shogi.Move{Source: hand, Dest: point(1, 0), PieceID: shogi.Fu0}, nil, ErrUchifuzume},
+		// not uchifuzume when the king can escape
+		{
+			"8k/9/7G1/9/9/9/9/9/4K4 b P 1",
+			&shogi.Move{Source: hand, Dest: point(1, 0), PieceID: shogi.Fu0},
+			&shogi.Move{Source: hand, Dest: point(1, 0), PieceID: shogi.Fu0},
+			nil,
+		},
+	}
+
+	for i, c := range cases {
+		b := newBoard(t, c.sfen)
+		res, err := b.Validate(c.in)
+		if !errors.Is(err, c.err) || !reflect.DeepEqual(res, c.want) {
+			t.Errorf(`
+[app > lib > rules > Board.Validate]
+Index:    %d
+Expected: %v, %v
+Actual:   %v, %v
+`, i, c.want, c.err, res, err)
+		}
+	}
+}
+
+func TestBoard_DoMove(t *testing.T) {
+	b := newBoard(t, startpos)
+	before := b.Position()
+
+	moves := []*shogi.Move{
+		{Source: point(6, 6), Dest: point(5, 6)},                   // 7g7f
+		{Source: point(2, 2), Dest: point(3, 2)},                   // 3c3d
+		{Source: point(7, 7), Dest: point(1, 1), IsPromoted: true}, // 8h2b+
+		{Source: point(0, 2), Dest: point(1, 1)},                   // 3a2b
+		{Source: hand, Dest: point(4, 4), PieceID: shogi.Kaku0},    // B*5e
+	}
+	for _, m := range moves {
+		if err := b.DoMove(m); err != nil {
+			t.Fatalf("[Board.DoMove] unexpected error: %v", err)
+		}
+	}
+
+	want := position(t, "lnsgkg1nl/1r5s1/pppppp1pp/6p2/4B4/2P6/PP1PPPPPP/7R1/LNSGKGSNL w b 6")
+	if res := b.Position(); !reflect.DeepEqual(res, want) {
+		t.Errorf(`
+[app > lib > rules > Board.DoMove]
+Expected: %v
+Actual:   %v
+`, want, res)
+	}
+
+	for range moves {
+		if err := b.UndoMove(); err != nil {
+			t.Fatalf("[Board.UndoMove] unexpected error: %v", err)
+		}
+	}
+	if res := b.Position(); !reflect.DeepEqual(res, before) {
+		t.Errorf(`
+[app > lib > rules > Board.UndoMove]
+Expected: %v
+Actual:   %v
+`, before, res)
+	}
+	if err := b.UndoMove(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("[Board.UndoMove] expected ErrNothingToUndo, but got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	valid := func() *shogi.Position { return position(t, startpos) }
+	cases := []struct {
+		modify func(*shogi.Position)
+		err    bool
+	}{
+		{func(*shogi.Position) {}, false},
+		{func(p *shogi.Position) { p.Pos = p.Pos[1:] }, true},
+		{func(p *shogi.Position) { p.Pos[0] = p.Pos[0][1:] }, true},
+		{func(p *shogi.Position) { p.Cap0 = p.Cap0[1:] }, true},
+		{func(p *shogi.Position) { p.Cap1[0] = -1 }, true},
+		{func(p *shogi.Position) { p.Turn = 0 }, true},
+		{func(p *shogi.Position) { p.Pos[4][4] = 9 }, true},
+		{func(p *shogi.Position) { p.Pos[4][4] = shogi.Gyoku1.ToInt() }, true},
+	}
+
+	for i, c := range cases {
+		pos := valid()
+		c.modify(pos)
+		b, err := New(pos)
+		if (err != nil) != c.err {
+			t.Errorf(`
+[app > lib > rules > New]
+Index:    %d
+Expected: error=%v
+Actual:   %v
+`, i, c.err, err)
+		}
+		if err == nil && !reflect.DeepEqual(b.Position(), pos) {
+			t.Errorf("[app > lib > rules > New] Index: %d, position was not kept", i)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import "github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+
+// PseudoLegalMoves returns the moves of the player to move which follow
+// the movements of pieces, forced promotions and the rules of drops
+// including nifu. The moves may leave the king in check, and pawn drops
+// may be uchifuzume.
+func (b *Board) PseudoLegalMoves() []*shogi.Move {
+	return toMoves(b.pseudoLegalMoves(nil))
+}
+
+// LegalMoves returns the legal moves of the player to move.
+func (b *Board) LegalMoves() []*shogi.Move {
+	return toMoves(b.legalMoves())
+}
+
+// InCheck returns true if the king of the player to move is attacked.
+func (b *Board) InCheck() bool {
+	king := b.kings[side(b.turn)]
+	return king != noSquare && b.attacked(king, -b.turn)
+}
+
+// IsCheckmate returns true if the player to move is in check,
+// and has no legal moves.
+func (b *Board) IsCheckmate() bool {
+	return b.InCheck() && !b.hasLegalMove()
+}
+
+// Perft returns the count of the leaf nodes of the legal move tree
+// of the depth. It is used to validate the move generator.
+func (b *Board) Perft(depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+
+	moves := b.legalMoves()
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+
+	var n uint64
+	for _, m := range moves {
+		b.doMove(m)
+		n += b.Perft(depth - 1)
+		b.undoMove()
+	}
+	return n
+}
+
+func toMoves(moves []move) []*shogi.Move {
+	res := make([]*shogi.Move, len(moves))
+	for i, m := range moves {
+		res[i] = m.toMove()
+	}
+	return res
+}
+
+// legalMoves returns the legal moves of the player to move.
+func (b *Board) legalMoves() []move {
+	moves := b.pseudoLegalMoves(nil)
+	legal := moves[:0]
+	for _, m := range moves {
+		if b.isLegal(m) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// hasLegalMove returns true if the player to move has any legal move.
+func (b *Board) hasLegalMove() bool {
+	for _, m := range b.pseudoLegalMoves(nil) {
+		if b.isLegal(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLegal returns true if the pseudo legal move does not leave the king
+// in check, and is not uchifuzume.
+func (b *Board) isLegal(m move) bool {
+	return !b.leavesInCheck(m) && !b.isUchifuzume(m)
+}
+
+// leavesInCheck returns true if the move leaves the player's king in check.
+func (b *Board) leavesInCheck(m move) bool {
+	us := b.turn
+	b.doMove(m)
+	king := b.kings[side(us)]
+	inCheck := king != noSquare && b.attacked(king, -us)
+	b.undoMove()
+	return inCheck
+}
+
+// isUchifuzume returns true if the move is a checkmate by dropping a pawn.
+func (b *Board) isUchifuzume(m move) bool {
+	if m.from != noSquare || kindOf(m.piece) != shogi.Fu0 {
+		return false
+	}
+	b.doMove(m)
+	mate := b.IsCheckmate()
+	b.undoMove()
+	return mate
+}
+
+// pseudoLegalMoves appends the pseudo legal moves to the slice.
+func (b *Board) pseudoLegalMoves(moves []move) []move {
+	us := b.turn
+	dir := int(us)
+
+	for from := 0; from < squares; from++ {
+		p := b.cells[from]
+		if p == shogi.Empty || ownerOf(p) != us {
+			continue
+		}
+
+		kind := kindOf(p)
+		row, column := from/columns, from%columns
+
+		for _, d := range steps[kind] {
+			r, c := row+d.row*dir, column+d.column*dir
+			if !inside(r, c) {
+				continue
+			}
+			to := square(r, c)
+			if t := b.cells[to]; t != shogi.Empty && ownerOf(t) == us {
+				continue
+			}
+			moves = b.appendMoves(moves, from, to, p)
+		}
+
+		for _, d := range slides[kind] {
+			r, c := row+d.row*dir, column+d.column*dir
+			for inside(r, c) {
+				to := square(r, c)
+				t := b.cells[to]
+				if t != shogi.Empty && ownerOf(t) == us {
+					break
+				}
+				moves = b.appendMoves(moves, from, to, p)
+				if t != shogi.Empty {
+					break
+				}
+				r, c = r+d.row*dir, c+d.column*dir
+			}
+		}
+	}
+
+	return b.appendDrops(moves)
+}
+
+// appendMoves appends the moves of the piece from the square to the square,
+// with and without promotion as allowed.
+func (b *Board) appendMoves(moves []move, from, to int, p shogi.Piece) []move {
+	us := b.turn
+	kind := kindOf(p)
+
+	if canPromote(kind) &&
+		(inPromotionZone(from/columns, us) || inPromotionZone(to/columns, us)) {
+		moves = append(moves, move{from: from, to: to, piece: p, promote: true})
+	}
+	if !isDead(kind, to/columns, us) {
+		moves = append(moves, move{from: from, to: to, piece: p, promote: false})
+	}
+	return moves
+}
+
+// appendDrops appends the drops of the player to move.
+func (b *Board) appendDrops(moves []move) []move {
+	us := b.turn
+	hand := &b.hands[side(us)]
+
+	// files which have the player's pawn, for nifu
+	var pawns [columns]bool
+	if hand[shogi.Fu0] > 0 {
+		fu := pieceOf(shogi.Fu0, us)
+		for sq := 0; sq < squares; sq++ {
+			if b.cells[sq] == fu {
+				pawns[sq%columns] = true
+			}
+		}
+	}
+
+	for kind := shogi.Fu0; kind <= shogi.Hisha0; kind++ {
+		if hand[kind] == 0 {
+			continue
+		}
+		p := pieceOf(kind, us)
+
+		for to := 0; to < squares; to++ {
+			if b.cells[to] != shogi.Empty || isDead(kind, to/columns, us) {
+				continue
+			}
+			if kind == shogi.Fu0 && pawns[to%columns] {
+				continue
+			}
+			moves = append(moves, move{from: noSquare, to: to, piece: p})
+		}
+	}
+
+	return moves
+}
+
+// attacked returns true if the square is attacked by the player's pieces.
+func (b *Board) attacked(sq int, by shogi.Turn) bool {
+	dir := int(by)
+	row, column := sq/columns, sq%columns
+
+	for kind := shogi.Fu0; kind < kinds; kind++ {
+		p := pieceOf(kind, by)
+
+		// the attacker is at the opposite direction of its move
+		for _, d := range steps[kind] {
+			r, c := row-d.row*dir, column-d.column*dir
+			if inside(r, c) && b.cells[square(r, c)] == p {
+				return true
+			}
+		}
+
+		for _, d := range slides[kind] {
+			r, c := row-d.row*dir, column-d.column*dir
+			for inside(r, c) {
+				t := b.cells[square(r, c)]
+				if t == p {
+					return true
+				}
+				if t != shogi.Empty {
+					break
+				}
+				r, c = r-d.row*dir, c-d.column*dir
+			}
+		}
+	}
+
+	return false
+}
+
+// doMove makes the pseudo legal move.
+func (b *Board) doMove(m move) {
+	us := b.turn
+	u := undo{move: m}
+
+	if m.from == noSquare {
+		b.hands[side(us)][kindOf(m.piece)]--
+		b.cells[m.to] = m.piece
+	} else {
+		if captured := b.cells[m.to]; captured != shogi.Empty {
+			u.captured = captured
+			b.hands[side(us)][unpromotedKind(captured)]++
+			if kindOf(captured) == shogi.Gyoku0 {
+				b.kings[side(-us)] = noSquare
+			}
+		}
+
+		p := m.piece
+		if m.promote {
+			p = promote(p)
+		}
+		b.cells[m.from] = shogi.Empty
+		b.cells[m.to] = p
+
+		if kindOf(p) == shogi.Gyoku0 {
+			b.kings[side(us)] = m.to
+		}
+	}
+
+	b.history = append(b.history, u)
+	b.turn = -us
+	b.moveCount++
+}
+
+// undoMove undoes the last move. The history must not be empty.
+func (b *Board) undoMove() {
+	u := b.history[len(b.history)-1]
+	b.history = b.history[:len(b.history)-1]
+
+	b.moveCount--
+	b.turn = -b.turn
+	us := b.turn
+	m := u.move
+
+	if m.from == noSquare {
+		b.cells[m.to] = shogi.Empty
+		b.hands[side(us)][kindOf(m.piece)]++
+		return
+	}
+
+	b.cells[m.from] = m.piece
+	b.cells[m.to] = u.captured
+	if kindOf(m.piece) == shogi.Gyoku0 {
+		b.kings[side(us)] = m.from
+	}
+
+	if u.captured != shogi.Empty {
+		b.hands[side(us)][unpromotedKind(u.captured)]--
+		if kindOf(u.captured) == shogi.Gyoku0 {
+			b.kings[side(-us)] = m.to
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestBoard_Perft(t *testing.T) {
+	cases := []struct {
+		sfen  string
+		depth int
+		want  uint64
+		long  bool
+	}{
+		{startpos, 1, 30, false},
+		{startpos, 2, 900, false},
+		{startpos, 3, 25470, false},
+		{startpos, 4, 719731, true},
+
+		// a position with many kinds of moves, known as 'matsuri'
+		{"l6nl/5+P1gk/2np1S3/p1p4Pp/3P2Sp1/1PPb2P1P/P5GS1/R8/LN4bKL w RGgsn5p 1", 1, 207, false},
+		{"l6nl/5+P1gk/2np1S3/p1p4Pp/3P2Sp1/1PPb2P1P/P5GS1/R8/LN4bKL w RGgsn5p 1", 2, 28684, false},
+		{"l6nl/5+P1gk/2np1S3/p1p4Pp/3P2Sp1/1PPb2P1P/P5GS1/R8/LN4bKL w RGgsn5p 1", 3, 4809015, true},
+
+		// the position with the most legal moves
+		{"R8/2K1S1SSk/4B4/9/9/9/9/9/1L1L1L3 b RBGSNLP3g3n17p 1", 1, 593, false},
+	}
+
+	for i, c := range cases {
+		if c.long && testing.Short() {
+			continue
+		}
+		b := newBoard(t, c.sfen)
+		if res := b.Perft(c.depth); res != c.want {
+			t.Errorf(`
+[app > lib > rules > Board.Perft]
+Index:    %d
+Depth:    %d
+Expected: %d
+Actual:   %d
+`, i, c.depth, c.want, res)
+		}
+	}
+}
+
+func TestBoard_LegalMoves(t *testing.T) {
+	cases := []struct {
+		name string
+		sfen string
+		want int
+	}{
+		// the king in check by the rook can only escape from the row
+		{"check", "4k4/9/9/9/9/9/9/9/4K3r b - 1", 3},
+		// pawns of both players must promote on the last row
+		{"forced promotion", "4k4/P8/9/9/9/9/9/9/4K4 b - 1", 6},
+		// kei cannot be dropped to the last two rows
+		{"dead piece drop", "4k4/9/9/9/9/9/9/9/4K4 b N 1", 5 + (79 - 17)},
+		// pawn cannot be dropped to the file of own pawn
+		{"nifu", "4k4/9/9/9/9/4P4/9/9/4K4 b P 1", 5 + 1 + (78 - 8 - 6)},
+		// dropping a pawn at 1b is checkmate
+		{"uchifuzume", "8k/9/6NG1/9/9/9/9/9/4K4 b P 1", 5 + 5 + 2 + (77 - 8 - 1)},
+	}
+
+	for i, c := range cases {
+		b := newBoard(t, c.sfen)
+		if res := b.LegalMoves(); len(res) != c.want {
+			t.Errorf(`
+[app > lib > rules > Board.LegalMoves] %s
+Index:    %d
+Expected: %d
+Actual:   %d
+`, c.name, i, c.want, len(res))
+		}
+	}
+}
+
+func TestBoard_InCheck(t *testing.T) {
+	cases := []struct {
+		sfen      string
+		inCheck   bool
+		checkmate bool
+	}{
+		{startpos, false, false},
+		{"4k4/9/9/9/9/9/9/9/4K3r b - 1", true, false},
+		{"4k4/4G4/4P4/9/9/9/9/9/4K4 w - 1", true, true},
+		// kei checks over pieces
+		{"4k4/9/9/9/9/9/3n5/4P4/4K4 b - 1", true, false},
+		{"4k4/9/9/9/9/9/9/4P4/4K3l b - 1", false, false},
+	}
+
+	for i, c := range cases {
+		b := newBoard(t, c.sfen)
+		if b.InCheck() != c.inCheck || b.IsCheckmate() != c.checkmate {
+			t.Errorf(`
+[app > lib > rules > Board.InCheck]
+Index:    %d
+Expected: %v, %v
+Actual:   %v, %v
+`, i, c.inCheck, c.checkmate, b.InCheck(), b.IsCheckmate())
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 murosan. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import "github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+
+// promotionOffset is the difference between a piece and its promoted piece.
+// e.g. shogi.To0 - shogi.Fu0
+const promotionOffset = shogi.To0 - shogi.Fu0
+
+// delta is a direction of moves, seen from the first player.
+// The row decreases when moving forward.
+type delta struct {
+	row    int
+	column int
+}
+
+var (
+	forward       = delta{-1, 0}
+	backward      = delta{1, 0}
+	left          = delta{0, -1}
+	right         = delta{0, 1}
+	forwardLeft   = delta{-1, -1}
+	forwardRight  = delta{-1, 1}
+	backwardLeft  = delta{1, -1}
+	backwardRight = delta{1, 1}
+
+	orthogonals = []delta{forward, backward, left, right}
+	diagonals   = []delta{forwardLeft, forwardRight, backwardLeft, backwardRight}
+	kinSteps    = []delta{forwardLeft, forward, forwardRight, left, right, backward}
+)
+
+// kinds is the size of arrays indexed by the kind of piece.
+const kinds = shogi.Ryu0 + 1
+
+// steps are the directions each kind of piece moves one square to.
+var steps = [kinds][]delta{
+	shogi.Fu0:       {forward},
+	shogi.Kei0:      {{-2, -1}, {-2, 1}},
+	shogi.Gin0:      {forwardLeft, forward, forwardRight, backwardLeft, backwardRight},
+	shogi.Kin0:      kinSteps,
+	shogi.Gyoku0:    append(append([]delta{}, orthogonals...), diagonals...),
+	shogi.To0:       kinSteps,
+	shogi.NariKyou0: kinSteps,
+	shogi.NariKei0:  kinSteps,
+	shogi.NariGin0:  kinSteps,
+	shogi.Uma0:      orthogonals,
+	shogi.Ryu0:      diagonals,
+}
+
+// slides are the directions each kind of piece moves any squares to.
+var slides = [kinds][]delta{
+	shogi.Kyou0:  {forward},
+	shogi.Kaku0:  diagonals,
+	shogi.Hisha0: orthogonals,
+	shogi.Uma0:   diagonals,
+	shogi.Ryu0:   orthogonals,
+}
+
+// kindOf returns the piece of the first player of the same kind.
+func kindOf(p shogi.Piece) shogi.Piece {
+	if p < 0 {
+		return -p
+	}
+	return p
+}
+
+// ownerOf returns the player who owns the piece. p must not be Empty.
+func ownerOf(p shogi.Piece) shogi.Turn {
+	if p > 0 {
+		return shogi.Sente
+	}
+	return shogi.Gote
+}
+
+// pieceOf returns the piece of the kind owned by the player.
+func pieceOf(kind shogi.Piece, owner shogi.Turn) shogi.Piece {
+	return kind * shogi.Piece(owner)
+}
+
+// isValidKind returns true if the kind is a piece of the first player.
+func isValidKind(kind shogi.Piece) bool {
+	return kind > shogi.Empty && kind < kinds &&
+		(steps[kind] != nil || slides[kind] != nil)
+}
+
+// canPromote returns true if the kind of piece can promote.
+func canPromote(kind shogi.Piece) bool {
+	switch kind {
+	case shogi.Fu0, shogi.Kyou0, shogi.Kei0, shogi.Gin0, shogi.Kaku0, shogi.Hisha0:
+		return true
+	}
+	return false
+}
+
+// promote returns the promoted piece. p must be able to promote.
+func promote(p shogi.Piece) shogi.Piece {
+	return pieceOf(kindOf(p)+promotionOffset, ownerOf(p))
+}
+
+// unpromotedKind returns the kind of the piece before promotion,
+// which is the kind to be put in the hand when captured.
+func unpromotedKind(p shogi.Piece) shogi.Piece {
+	kind := kindOf(p)
+	if kind > shogi.Gyoku0 {
+		return kind - promotionOffset
+	}
+	return kind
+}
+
+// relativeRow returns the row seen from the player.
+// The furthest row is 0.
+func relativeRow(row int, player shogi.Turn) int {
+	if player == shogi.Sente {
+		return row
+	}
+	return rows - 1 - row
+}
+
+// isDead returns true if the piece of the kind has no move at the row.
+// Such moves and drops are not allowed.
+func isDead(kind shogi.Piece, row int, player shogi.Turn) bool {
+	r := relativeRow(row, player)
+	switch kind {
+	case shogi.Fu0, shogi.Kyou0:
+		return r == 0
+	case shogi.Kei0:
+		return r <= 1
+	}
+	return false
+}
+
+// inPromotionZone returns true if the row is in the player's promotion zone.
+func inPromotionZone(row int, player shogi.Turn) bool {
+	return relativeRow(row, player) <= 2
+}
@@ -0,0 +1,56 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+// Move converts shogi.Move to usi move string. e.g. 7g7f, 8h2b+, G*5b
+// The piece of a drop is always written in upper case,
+// regardless of the player.
+func Move(m *shogi.Move) (string, error) {
+	if m == nil || m.Source == nil || m.Dest == nil {
+		return "", errors.New("source and dest of the move are required")
+	}
+
+	dst, err := point(m.Dest)
+	if err != nil {
+		return "", err
+	}
+
+	// is from captured.
+	if m.Source.Row == -1 && m.Source.Column == -1 {
+		id := m.PieceID
+		if id < 0 {
+			id = -id
+		}
+		if id < shogi.Fu0 || id > shogi.Hisha0 {
+			return "", errors.New("the piece cannot be dropped. id=" + fmt.Sprint(m.PieceID))
+		}
+		p, err := Piece(id)
+		if err != nil {
+			return "", err
+		}
+		return p.String() + "*" + dst, nil
+	}
+
+	src, err := point(m.Source)
+	if err != nil {
+		return "", err
+	}
+
+	s := src + dst
+	if m.IsPromoted {
+		s += "+"
+	}
+	return s, nil
+}
+
+func point(p *shogi.Point) (string, error) {
+	if p.Row < 0 || p.Row > 8 || p.Column < 0 || p.Column > 8 {
+		return "", fmt.Errorf("invalid point. row=%d, column=%d", p.Row, p.Column)
+	}
+	return fmt.Sprint(p.Column+1) + string(rune('a'+p.Row)), nil
+}
@@ -0,0 +1,82 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+func TestMove(t *testing.T) {
+	cases := []struct {
+		in   *shogi.Move
+		want string
+		err  bool
+	}{
+		{
+			&shogi.Move{
+				Source: &shogi.Point{Row: 6, Column: 6},
+				Dest:   &shogi.Point{Row: 5, Column: 6},
+			},
+			"7g7f",
+			false,
+		},
+		{
+			&shogi.Move{
+				Source:     &shogi.Point{Row: 7, Column: 7},
+				Dest:       &shogi.Point{Row: 1, Column: 1},
+				PieceID:    shogi.Kaku0,
+				IsPromoted: true,
+			},
+			"8h2b+",
+			false,
+		},
+		{
+			&shogi.Move{
+				Source:  &shogi.Point{Row: -1, Column: -1},
+				Dest:    &shogi.Point{Row: 1, Column: 4},
+				PieceID: shogi.Kin0,
+			},
+			"G*5b",
+			false,
+		},
+		{
+			&shogi.Move{
+				Source:  &shogi.Point{Row: -1, Column: -1},
+				Dest:    &shogi.Point{Row: 7, Column: 4},
+				PieceID: shogi.Gin1,
+			},
+			"S*5h",
+			false,
+		},
+		{nil, "", true},
+		{&shogi.Move{Dest: &shogi.Point{Row: 1, Column: 4}}, "", true},
+		{
+			&shogi.Move{
+				Source: &shogi.Point{Row: 6, Column: 6},
+				Dest:   &shogi.Point{Row: 9, Column: 6},
+			},
+			"",
+			true,
+		},
+		{
+			&shogi.Move{
+				Source:  &shogi.Point{Row: -1, Column: -1},
+				Dest:    &shogi.Point{Row: 1, Column: 4},
+				PieceID: shogi.Gyoku0,
+			},
+			"",
+			true,
+		},
+	}
+
+	for i, c := range cases {
+		res, err := Move(c.in)
+		if (err != nil) != c.err || res != c.want {
+			t.Errorf(`[Move]
+Index:    %d
+Expected: %s, error=%v
+Actual:   %s, %v
+`, i, c.want, c.err, res, err)
+		}
+	}
+}
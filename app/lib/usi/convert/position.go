@@ -9,14 +9,36 @@ import (
 	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
 )
 
+// positionPrefix is the prefix of usi-position command with sfen.
+const positionPrefix = "position sfen "
+
 // Position converts shogi.Position to usi-position command bytes.
 func Position(p *shogi.Position) ([]byte, error) {
+	sfen, err := SFEN(p)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(positionPrefix + sfen), nil
+}
+
+// PositionWithMoves returns usi-position command bytes of the SFEN
+// followed by the usi moves.
+func PositionWithMoves(sfen string, moves []string) []byte {
+	s := positionPrefix + sfen
+	if len(moves) > 0 {
+		s += " moves " + strings.Join(moves, " ")
+	}
+	return []byte(s)
+}
+
+// SFEN converts shogi.Position to SFEN string.
+func SFEN(p *shogi.Position) (string, error) {
 	// for safety
 	if len(p.Pos) != 9 {
-		return nil, errors.New("length of position.Pos is not 9")
+		return "", errors.New("length of position.Pos is not 9")
 	}
 	if len(p.Cap0) != 7 || len(p.Cap1) != 7 {
-		return nil, errors.New("length of position.Cap* is not 7")
+		return "", errors.New("length of position.Cap* is not 7")
 	}
 
 	rows := make([]string, 9)
@@ -24,18 +46,18 @@ func Position(p *shogi.Position) ([]byte, error) {
 	for i, r := range p.Pos {
 		usir, err := rowToUSI(r)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 		rows[i] = usir
 	}
 
-	s := []byte("position sfen " + strings.Join(rows, "/"))
+	s := []byte(strings.Join(rows, "/"))
 	if p.Turn == shogi.Sente {
 		s = append(s, []byte(" "+usi.Sente+" ")...)
 	} else if p.Turn == shogi.Gote {
 		s = append(s, []byte(" "+usi.Gote+" ")...)
 	} else {
-		return nil, errors.New("unknown turn number. Turn = " + fmt.Sprint(p.Turn))
+		return "", errors.New("unknown turn number. Turn = " + fmt.Sprint(p.Turn))
 	}
 
 	caps := []byte("")
@@ -44,7 +66,7 @@ func Position(p *shogi.Position) ([]byte, error) {
 		if c != 0 {
 			p, err := Piece(shogi.Piece(i + 1))
 			if err != nil {
-				return nil, err
+				return "", err
 			}
 			caps = append(caps, []byte(fmt.Sprint(c)+p.String())...)
 		}
@@ -54,7 +76,7 @@ func Position(p *shogi.Position) ([]byte, error) {
 		if c != 0 {
 			p, err := Piece(shogi.Piece(-i - 1))
 			if err != nil {
-				return nil, err
+				return "", err
 			}
 			caps = append(caps, []byte(fmt.Sprint(c)+p.String())...)
 		}
@@ -66,7 +88,7 @@ func Position(p *shogi.Position) ([]byte, error) {
 		s = append(s, caps...)
 	}
 
-	return append(s, []byte(" "+fmt.Sprint(p.MoveCount))...), nil
+	return string(append(s, []byte(" "+fmt.Sprint(p.MoveCount))...)), nil
 }
 
 func rowToUSI(row []int) (s string, err error) {
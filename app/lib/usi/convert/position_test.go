@@ -200,3 +200,32 @@ Expected: %v
 Actual:   %v
 `, msg, i, in, expected, actual)
 }
+
+func TestSFEN(t *testing.T) {
+	pos := &shogi.Position{
+		Pos: [][]int{
+			{-2, -3, -4, -5, -8, -5, -4, -3, -2},
+			{0, -7, 0, 0, 0, 0, 0, -6, 0},
+			{-1, -1, -1, -1, -1, -1, -1, -1, -1},
+			{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{1, 1, 1, 1, 1, 1, 1, 1, 1},
+			{0, 6, 0, 0, 0, 0, 0, 7, 0},
+			{2, 3, 4, 5, 8, 5, 4, 3, 2},
+		},
+		Cap0:      []int{0, 0, 0, 0, 0, 0, 0},
+		Cap1:      []int{0, 0, 0, 0, 0, 0, 0},
+		Turn:      1,
+		MoveCount: 1,
+	}
+	want := "lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1"
+
+	s, err := SFEN(pos)
+	if err != nil || s != want {
+		t.Errorf(`[SFEN]
+Expected: %s
+Actual:   %s (err=%v)
+`, want, s, err)
+	}
+}
@@ -0,0 +1,51 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+const (
+	bestMove = "bestmove"
+	ponder   = "ponder"
+	resign   = "resign"
+	win      = "win"
+)
+
+// BestMove generates usi.BestMove parsing from given string, and returns it
+func BestMove(s string) (*usi.BestMove, error) {
+	a := strings.Fields(s)
+
+	if len(a) < 2 || a[0] != bestMove {
+		return nil, errors.New("invalid bestmove. input = " + s)
+	}
+
+	switch a[1] {
+	case resign:
+		return &usi.BestMove{Resign: true}, nil
+	case win:
+		return &usi.BestMove{Win: true}, nil
+	}
+
+	mv, err := Move(a[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bestmove. input = %s: %w", s, err)
+	}
+	r := &usi.BestMove{Move: mv}
+
+	if len(a) >= 3 && a[2] == ponder {
+		if len(a) < 4 {
+			return nil, errors.New("ponder move is missing. input = " + s)
+		}
+		p, err := Move(a[3])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ponder. input = %s: %w", s, err)
+		}
+		r.Ponder = p
+	}
+
+	return r, nil
+}
@@ -0,0 +1,49 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+func TestBestMove(t *testing.T) {
+	mv7g7f := &shogi.Move{
+		Source: &shogi.Point{Row: 6, Column: 6},
+		Dest:   &shogi.Point{Row: 5, Column: 6},
+	}
+	mv3c3d := &shogi.Move{
+		Source: &shogi.Point{Row: 2, Column: 2},
+		Dest:   &shogi.Point{Row: 3, Column: 2},
+	}
+
+	cases := []struct {
+		in   string
+		want *usi.BestMove
+		err  error
+	}{
+		{"bestmove 7g7f", &usi.BestMove{Move: mv7g7f}, nil},
+		{"bestmove 7g7f ponder 3c3d", &usi.BestMove{Move: mv7g7f, Ponder: mv3c3d}, nil},
+		{"bestmove resign", &usi.BestMove{Resign: true}, nil},
+		{"bestmove win", &usi.BestMove{Win: true}, nil},
+		{"bestmove", nil, errEmp},
+		{"bestmove 7g7z", nil, errEmp},
+		{"bestmove 7g7f ponder", nil, errEmp},
+		{"bestmove 7g7f ponder 3c3z", nil, errEmp},
+		{"info depth 1", nil, errEmp},
+	}
+
+	for i, c := range cases {
+		res, err := BestMove(c.in)
+
+		if (err == nil) != (c.err == nil) || !reflect.DeepEqual(res, c.want) {
+			t.Errorf(`[BestMove]
+Index:    %d
+Input:    %s
+Expected: %v, %v
+Actual:   %v, %v
+`, i, c.in, c.want, c.err, res, err)
+		}
+	}
+}
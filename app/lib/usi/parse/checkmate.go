@@ -0,0 +1,37 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+const checkmate = "checkmate"
+
+// Checkmate generates usi.Checkmate parsing from given string, and returns it
+func Checkmate(s string) (*usi.Checkmate, error) {
+	a := strings.Fields(s)
+
+	if len(a) < 2 || a[0] != checkmate {
+		return nil, errors.New("invalid checkmate. input = " + s)
+	}
+
+	switch usi.CheckmateStatus(a[1]) {
+	case usi.NoMate, usi.Timeout, usi.NotImplemented:
+		return &usi.Checkmate{Status: usi.CheckmateStatus(a[1])}, nil
+	}
+
+	moves := make([]*shogi.Move, len(a[1:]))
+	for i, v := range a[1:] {
+		mv, err := Move(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse checkmate. input = %s: %w", s, err)
+		}
+		moves[i] = mv
+	}
+
+	return &usi.Checkmate{Status: usi.Mate, Moves: moves}, nil
+}
@@ -0,0 +1,55 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+func TestCheckmate(t *testing.T) {
+	cases := []struct {
+		in   string
+		want *usi.Checkmate
+		err  error
+	}{
+		{
+			"checkmate G*5b 5a5b",
+			&usi.Checkmate{
+				Status: usi.Mate,
+				Moves: []*shogi.Move{
+					{
+						Source:  &shogi.Point{Row: -1, Column: -1},
+						Dest:    &shogi.Point{Row: 1, Column: 4},
+						PieceID: shogi.Kin0,
+					},
+					{
+						Source: &shogi.Point{Row: 0, Column: 4},
+						Dest:   &shogi.Point{Row: 1, Column: 4},
+					},
+				},
+			},
+			nil,
+		},
+		{"checkmate nomate", &usi.Checkmate{Status: usi.NoMate}, nil},
+		{"checkmate timeout", &usi.Checkmate{Status: usi.Timeout}, nil},
+		{"checkmate notimplemented", &usi.Checkmate{Status: usi.NotImplemented}, nil},
+		{"checkmate", nil, errEmp},
+		{"checkmate 5a5z", nil, errEmp},
+		{"bestmove 7g7f", nil, errEmp},
+	}
+
+	for i, c := range cases {
+		res, err := Checkmate(c.in)
+
+		if (err == nil) != (c.err == nil) || !reflect.DeepEqual(res, c.want) {
+			t.Errorf(`[Checkmate]
+Index:    %d
+Input:    %s
+Expected: %v, %v
+Actual:   %v, %v
+`, i, c.in, c.want, c.err, res, err)
+		}
+	}
+}
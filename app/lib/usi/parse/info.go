@@ -11,21 +11,51 @@ import (
 )
 
 const (
-	depth    = "depth"
-	selDepth = "seldepth"
-	time     = "time"
-	nodes    = "nodes"
-	hashFull = "hashfull"
-	nps      = "nps"
-	score    = "score"
-	pv       = "pv"
-	multiPv  = "multipv"
+	depth          = "depth"
+	selDepth       = "seldepth"
+	time           = "time"
+	nodes          = "nodes"
+	hashFull       = "hashfull"
+	nps            = "nps"
+	currMoveNumber = "currmovenumber"
+	cpuLoad        = "cpuload"
+	score          = "score"
+	pv             = "pv"
+	multiPv        = "multipv"
+	currMove       = "currmove"
+	refutation     = "refutation"
+	currLine       = "currline"
+	str            = "string"
+
+	scoreCP    = "cp"
+	scoreMate  = "mate"
+	lowerBound = "lowerbound"
+	upperBound = "upperbound"
 )
 
+// keywords is a set of known info keywords.
+var keywords = map[string]bool{
+	depth: true, selDepth: true, time: true, nodes: true, hashFull: true,
+	nps: true, currMoveNumber: true, cpuLoad: true, score: true, pv: true,
+	multiPv: true, currMove: true, refutation: true, currLine: true, str: true,
+	lowerBound: true, upperBound: true,
+}
+
+// infoStringPrefix is the prefix of 'info string'.
+const infoStringPrefix = "info string"
+
+// InfoString returns the message of 'info string'.
+func InfoString(s string) (string, error) {
+	if !strings.HasPrefix(s, infoStringPrefix) {
+		return "", errors.New("not 'info string'. input = " + s)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(s, infoStringPrefix)), nil
+}
+
 // Info generates engine.Info parsing from given string, and returns it
 func Info(s string) (*usi.Info, int, error) {
 	// should not pass 'info string'
-	if strings.HasPrefix(s, "info string") {
+	if strings.HasPrefix(s, infoStringPrefix) {
 		return nil, 0, errors.New("'info string' was given")
 	}
 
@@ -35,74 +65,109 @@ func Info(s string) (*usi.Info, int, error) {
 
 	nan := "given value was not a number. value = "
 
+	// next returns the next token, or error if there is no more.
+	next := func(i int) (string, error) {
+		if i+1 >= len(a) {
+			return "", errors.New("value is missing. key = " + a[i] + ", input = " + s)
+		}
+		return a[i+1], nil
+	}
+
+	// moves parses tokens from a[i] as moves until the next keyword,
+	// and returns them with the index of the last token.
+	moves := func(i int) ([]*shogi.Move, int, error) {
+		m := make([]*shogi.Move, 0)
+		for ; i < len(a) && !keywords[a[i]]; i++ {
+			mv, err := Move(a[i])
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to parse move. value = "+a[i]+": %w", err)
+			}
+			m = append(m, mv)
+		}
+		return m, i - 1, nil
+	}
+
 	i := 0
 	for i < len(a) {
-		switch strings.TrimSpace(a[i]) {
-		case depth:
-			i++
-			n, err := strconv.Atoi(a[i])
+		switch key := strings.TrimSpace(a[i]); key {
+		case depth, selDepth, time, nodes, hashFull, nps, currMoveNumber, cpuLoad:
+			v, err := next(i)
 			if err != nil {
-				return nil, 0, fmt.Errorf(nan+a[i]+": %w", err)
+				return nil, 0, err
 			}
-			r.Values[depth] = n
-
-		case selDepth:
 			i++
-			n, err := strconv.Atoi(a[i])
+			n, err := strconv.Atoi(v)
 			if err != nil {
-				return nil, 0, fmt.Errorf(nan+a[i]+": %w", err)
+				return nil, 0, fmt.Errorf(nan+v+": %w", err)
 			}
-			r.Values[selDepth] = n
+			r.Values[key] = n
 
-		case time:
-			i++
-			n, err := strconv.Atoi(a[i])
-			if err != nil {
-				return nil, 0, fmt.Errorf(nan+a[i]+": %w", err)
+		case score:
+			if i+2 >= len(a) {
+				return nil, 0, errors.New("insufficient score. input = " + s)
+			}
+			if err := parseScore(r, a[i+1], a[i+2]); err != nil {
+				return nil, 0, err
 			}
-			r.Values[time] = n
+			i += 2
 
-		case nodes:
-			i++
-			n, err := strconv.Atoi(a[i])
+		case lowerBound:
+			r.LowerBound = true
+
+		case upperBound:
+			r.UpperBound = true
+
+		case multiPv:
+			v, err := next(i)
 			if err != nil {
-				return nil, 0, fmt.Errorf(nan+a[i]+": %w", err)
+				return nil, 0, err
 			}
-			r.Values[nodes] = n
-
-		case hashFull:
 			i++
-			n, err := strconv.Atoi(a[i])
+			n, err := strconv.Atoi(v)
 			if err != nil {
-				return nil, 0, fmt.Errorf(nan+a[i]+": %w", err)
+				return nil, 0, fmt.Errorf(nan+v+": %w", err)
 			}
-			r.Values[hashFull] = n
+			mpv = n
 
-		case nps:
+		case currMove:
+			v, err := next(i)
+			if err != nil {
+				return nil, 0, err
+			}
 			i++
-			n, err := strconv.Atoi(a[i])
+			mv, err := Move(v)
 			if err != nil {
-				return nil, 0, fmt.Errorf(nan+a[i]+": %w", err)
+				return nil, 0, fmt.Errorf("failed to parse currmove. value = "+v+": %w", err)
 			}
-			r.Values[nps] = n
+			r.CurrMove = mv
 
-		case score:
-			if a[i+1] == "cp" || a[i+1] == "mate" {
-				n, err := strconv.Atoi(a[i+2])
-				if err != nil {
-					return nil, 0, fmt.Errorf(nan+a[i]+": %w", err)
+		case refutation:
+			m, last, err := moves(i + 1)
+			if err != nil {
+				return nil, 0, err
+			}
+			r.Refutation = m
+			i = last
+
+		case currLine:
+			// currline [cpunr] <move1> ... <movei>
+			if v, err := next(i); err == nil {
+				if n, err := strconv.Atoi(v); err == nil {
+					r.CurrLineCPU = n
+					i++
 				}
-				r.Score = n
 			}
-			i += 2
-
-		case multiPv:
-			i++
-			n, err := strconv.Atoi(a[i])
+			m, last, err := moves(i + 1)
 			if err != nil {
-				return nil, 0, fmt.Errorf(nan+a[i]+": %w", err)
+				return nil, 0, err
 			}
-			mpv = n
+			r.CurrLine = m
+			i = last
+
+		case str:
+			// the rest of the line is the string
+			r.String = strings.Join(a[i+1:], " ")
+			i += len(a) // force to end this loop
 
 		case pv:
 			m := make([]*shogi.Move, len(a[i+1:]))
@@ -115,9 +180,61 @@ func Info(s string) (*usi.Info, int, error) {
 			}
 			r.Moves = m
 			i += len(a) // force to end this loop, because pv must be in ending of info.
+
+		case "info", "":
+			// skip
+
+		default:
+			// keep unknown tokens with their values until the next keyword
+			j := i + 1
+			for j < len(a) && !keywords[a[j]] {
+				j++
+			}
+			if r.Others == nil {
+				r.Others = make(map[string]string)
+			}
+			r.Others[key] = strings.Join(a[i+1:j], " ")
+			i = j - 1
 		}
 		i++
 	}
 
 	return r, mpv, nil
 }
+
+// parseScore sets the score of the info from the given kind and value.
+//
+//	cp <x>
+//	mate <y>
+//	mate +
+//	mate -
+func parseScore(r *usi.Info, kind, value string) error {
+	switch kind {
+	case scoreCP:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("given value was not a number. value = "+value+": %w", err)
+		}
+		r.Score = n
+		r.ScoreType = usi.ScoreCP
+	case scoreMate:
+		switch value {
+		case "+":
+			r.Score = 1
+			r.ScoreType = usi.ScoreMateUnknown
+		case "-":
+			r.Score = -1
+			r.ScoreType = usi.ScoreMateUnknown
+		default:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("given value was not a number. value = "+value+": %w", err)
+			}
+			r.Score = n
+			r.ScoreType = usi.ScoreMate
+		}
+	default:
+		return errors.New("unknown score type. type = " + kind)
+	}
+	return nil
+}
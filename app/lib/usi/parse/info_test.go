@@ -25,7 +25,8 @@ func TestInfo(t *testing.T) {
 					selDepth: 3,
 					nodes:    135125,
 				},
-				Score: -1521,
+				Score:     -1521,
+				ScoreType: usi.ScoreCP,
 				Moves: []*shogi.Move{
 					{
 						Source:     &shogi.Point{Row: 0, Column: 2},
@@ -67,8 +68,9 @@ func TestInfo(t *testing.T) {
 		{
 			"info score cp 156 multipv 1 pv P*5h 4g5g 5h5g 8b8f",
 			&usi.Info{
-				Values: make(map[string]int),
-				Score:  156,
+				Values:    make(map[string]int),
+				Score:     156,
+				ScoreType: usi.ScoreCP,
 				Moves: []*shogi.Move{
 					{
 						Source:     &shogi.Point{Row: -1, Column: -1},
@@ -102,8 +104,9 @@ func TestInfo(t *testing.T) {
 		{
 			"info score cp -99 multipv 2 pv 2d4d 3c4e 8h5e N*7f",
 			&usi.Info{
-				Values: make(map[string]int),
-				Score:  -99,
+				Values:    make(map[string]int),
+				Score:     -99,
+				ScoreType: usi.ScoreCP,
 				Moves: []*shogi.Move{
 					{
 						Source:     &shogi.Point{Row: 3, Column: 1},
@@ -137,8 +140,9 @@ func TestInfo(t *testing.T) {
 		{
 			"info score cp -157 multipv 3 pv 5g5f 4g4f 4e3c+ 4c3c",
 			&usi.Info{
-				Values: make(map[string]int),
-				Score:  -157,
+				Values:    make(map[string]int),
+				Score:     -157,
+				ScoreType: usi.ScoreCP,
 				Moves: []*shogi.Move{
 					{
 						Source:     &shogi.Point{Row: 6, Column: 4},
@@ -173,8 +177,9 @@ func TestInfo(t *testing.T) {
 		{
 			"info score cp -157 str multipv 3 lalala... pv 5g5f 4g4f 4e3c+ 4c3c",
 			&usi.Info{
-				Values: make(map[string]int),
-				Score:  -157,
+				Values:    make(map[string]int),
+				Score:     -157,
+				ScoreType: usi.ScoreCP,
 				Moves: []*shogi.Move{
 					{
 						Source:     &shogi.Point{Row: 6, Column: 4},
@@ -202,6 +207,8 @@ func TestInfo(t *testing.T) {
 						IsPromoted: false,
 					},
 				},
+				// unknown tokens are kept until the next keyword
+				Others: map[string]string{"str": "", "lalala...": ""},
 			},
 			3,
 			nil,
@@ -209,8 +216,9 @@ func TestInfo(t *testing.T) {
 		{
 			"info score cp -225 multipv 4 pv 5g6h 8b8f P*8g 8f5f",
 			&usi.Info{
-				Values: make(map[string]int),
-				Score:  -225,
+				Values:    make(map[string]int),
+				Score:     -225,
+				ScoreType: usi.ScoreCP,
 				Moves: []*shogi.Move{
 					{
 						Source:     &shogi.Point{Row: 6, Column: 4},
@@ -253,6 +261,161 @@ func TestInfo(t *testing.T) {
 			0,
 			errEmp,
 		},
+		{
+			"info depth 10 score cp 120 lowerbound",
+			&usi.Info{
+				Values:     map[string]int{depth: 10},
+				Score:      120,
+				ScoreType:  usi.ScoreCP,
+				LowerBound: true,
+			},
+			0,
+			nil,
+		},
+		{
+			"info depth 10 score cp -30 upperbound nodes 100",
+			&usi.Info{
+				Values:     map[string]int{depth: 10, nodes: 100},
+				Score:      -30,
+				ScoreType:  usi.ScoreCP,
+				UpperBound: true,
+			},
+			0,
+			nil,
+		},
+		{
+			"info score mate 5 pv 7g7f",
+			&usi.Info{
+				Values:    make(map[string]int),
+				Score:     5,
+				ScoreType: usi.ScoreMate,
+				Moves: []*shogi.Move{
+					{
+						Source:     &shogi.Point{Row: 6, Column: 6},
+						Dest:       &shogi.Point{Row: 5, Column: 6},
+						PieceID:    0,
+						IsPromoted: false,
+					},
+				},
+			},
+			0,
+			nil,
+		},
+		{
+			"info score mate -4",
+			&usi.Info{Values: make(map[string]int), Score: -4, ScoreType: usi.ScoreMate},
+			0,
+			nil,
+		},
+		{
+			"info score mate +",
+			&usi.Info{Values: make(map[string]int), Score: 1, ScoreType: usi.ScoreMateUnknown},
+			0,
+			nil,
+		},
+		{
+			"info score mate - multipv 2",
+			&usi.Info{Values: make(map[string]int), Score: -1, ScoreType: usi.ScoreMateUnknown},
+			2,
+			nil,
+		},
+		{
+			"info score mate 3 lowerbound",
+			&usi.Info{
+				Values:     make(map[string]int),
+				Score:      3,
+				ScoreType:  usi.ScoreMate,
+				LowerBound: true,
+			},
+			0,
+			nil,
+		},
+		{
+			"info nodes 100",
+			&usi.Info{Values: map[string]int{nodes: 100}},
+			0,
+			nil,
+		},
+		{
+			"info depth 5 currmove 7g7f currmovenumber 12 cpuload 500",
+			&usi.Info{
+				Values: map[string]int{depth: 5, currMoveNumber: 12, cpuLoad: 500},
+				CurrMove: &shogi.Move{
+					Source: &shogi.Point{Row: 6, Column: 6},
+					Dest:   &shogi.Point{Row: 5, Column: 6},
+				},
+			},
+			0,
+			nil,
+		},
+		{
+			"info refutation 7g7f 3c3d depth 3",
+			&usi.Info{
+				Values: map[string]int{depth: 3},
+				Refutation: []*shogi.Move{
+					{
+						Source: &shogi.Point{Row: 6, Column: 6},
+						Dest:   &shogi.Point{Row: 5, Column: 6},
+					},
+					{
+						Source: &shogi.Point{Row: 2, Column: 2},
+						Dest:   &shogi.Point{Row: 3, Column: 2},
+					},
+				},
+			},
+			0,
+			nil,
+		},
+		{
+			"info currline 2 7g7f nodes 10",
+			&usi.Info{
+				Values: map[string]int{nodes: 10},
+				CurrLine: []*shogi.Move{
+					{
+						Source: &shogi.Point{Row: 6, Column: 6},
+						Dest:   &shogi.Point{Row: 5, Column: 6},
+					},
+				},
+				CurrLineCPU: 2,
+			},
+			0,
+			nil,
+		},
+		{
+			"info currline 7g7f",
+			&usi.Info{
+				Values: make(map[string]int),
+				CurrLine: []*shogi.Move{
+					{
+						Source: &shogi.Point{Row: 6, Column: 6},
+						Dest:   &shogi.Point{Row: 5, Column: 6},
+					},
+				},
+			},
+			0,
+			nil,
+		},
+		{
+			"info depth 1 string book hit 7g7f",
+			&usi.Info{Values: map[string]int{depth: 1}, String: "book hit 7g7f"},
+			0,
+			nil,
+		},
+		{
+			"info foo 1 2 bar depth 1",
+			&usi.Info{
+				Values: map[string]int{depth: 1},
+				Others: map[string]string{"foo": "1 2 bar"},
+			},
+			0,
+			nil,
+		},
+		{"info depth", nil, 0, errEmp},
+		{"info currmove 7z7f", nil, 0, errEmp},
+		{"info refutation 7g7f 3c3z", nil, 0, errEmp},
+		{"info score mate a", nil, 0, errEmp},
+		{"info score unknown 10", nil, 0, errEmp},
+		{"info score cp", nil, 0, errEmp},
 		{"info string test", nil, 0, errEmp},
 		{"info nodes a nps 116391 hashfull 104", nil, 0, errEmp},
 		{"info nodes 12000 nps a hashfull 104", nil, 0, errEmp},
@@ -312,7 +475,10 @@ func infoHelper(
 	}
 
 	valuesEquals := reflect.DeepEqual(want.Values, res.Values)
-	scoreEquals := want.Score == res.Score
+	scoreEquals := want.Score == res.Score &&
+		want.ScoreType == res.ScoreType &&
+		want.LowerBound == res.LowerBound &&
+		want.UpperBound == res.UpperBound
 	movesEquals := func() bool {
 		if len(want.Moves) != len(res.Moves) {
 			return false
@@ -325,7 +491,14 @@ func infoHelper(
 		return true
 	}()
 
-	if !valuesEquals || !scoreEquals || !movesEquals {
+	othersEquals := reflect.DeepEqual(want.CurrMove, res.CurrMove) &&
+		reflect.DeepEqual(want.Refutation, res.Refutation) &&
+		reflect.DeepEqual(want.CurrLine, res.CurrLine) &&
+		want.CurrLineCPU == res.CurrLineCPU &&
+		want.String == res.String &&
+		reflect.DeepEqual(want.Others, res.Others)
+
+	if !valuesEquals || !scoreEquals || !movesEquals || !othersEquals {
 		msg = "The value was not as expected."
 		infoErrorPrintHelper(t, i, msg, in, want, res)
 	}
@@ -347,3 +520,28 @@ Expected: %v
 Actual:   %v
 `, msg, i, in, expected, actual)
 }
+
+func TestInfoString(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"info string loading eval file", "loading eval file", nil},
+		{"info string  book hit ", "book hit", nil},
+		{"info string", "", nil},
+		{"info depth 1", "", errEmp},
+	}
+
+	for i, c := range cases {
+		res, err := InfoString(c.in)
+		if (err == nil) != (c.err == nil) || res != c.want {
+			t.Errorf(`[InfoString]
+Index:    %d
+Input:    %s
+Expected: %s, %v
+Actual:   %s, %v
+`, i, c.in, c.want, c.err, res, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+)
+
+// StartPos is the SFEN of the initial position of shogi.
+const StartPos = "lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1"
+
+// Position parses usi-position, and returns the position and the moves.
+// The formats are below, and the 'position' prefix can be omitted.
+//
+//	position sfen <sfen> [moves <move1> ... <moveN>]
+//	position startpos [moves <move1> ... <moveN>]
+//
+// The moves are only parsed, not validated on the position.
+func Position(s string) (*shogi.Position, []*shogi.Move, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 0 && fields[0] == "position" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return nil, nil, errors.New("empty position. input = " + s)
+	}
+
+	// split at 'moves'
+	var moveFields []string
+	for i, f := range fields {
+		if f == "moves" {
+			moveFields = fields[i+1:]
+			fields = fields[:i]
+			break
+		}
+	}
+
+	var sfen string
+	switch fields[0] {
+	case "startpos":
+		if len(fields) != 1 {
+			return nil, nil, errors.New("unexpected tokens after startpos. input = " + s)
+		}
+		sfen = StartPos
+	case "sfen":
+		sfen = strings.Join(fields[1:], " ")
+	default:
+		return nil, nil, errors.New("position must start with sfen or startpos. input = " + s)
+	}
+
+	pos, err := SFEN(sfen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	moves := make([]*shogi.Move, len(moveFields))
+	for i, f := range moveFields {
+		m, err := Move(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse moves[%d]: %w", i, err)
+		}
+		moves[i] = m
+	}
+
+	return pos, moves, nil
+}
+
+// SFEN parses SFEN string, and returns the position.
+// The move count can be omitted, and then it is 1.
+func SFEN(s string) (*shogi.Position, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 && len(fields) != 4 {
+		return nil, errors.New("sfen must have 3 or 4 fields. input = " + s)
+	}
+
+	pos := &shogi.Position{
+		Cap0:      make([]int, 7),
+		Cap1:      make([]int, 7),
+		MoveCount: 1,
+	}
+
+	rows := strings.Split(fields[0], "/")
+	if len(rows) != 9 {
+		return nil, errors.New("the board of sfen must have 9 rows. input = " + fields[0])
+	}
+	for _, r := range rows {
+		row, err := parseSFENRow(r)
+		if err != nil {
+			return nil, err
+		}
+		pos.Pos = append(pos.Pos, row)
+	}
+
+	switch usi.Turn(fields[1]) {
+	case usi.Sente:
+		pos.Turn = shogi.Sente
+	case usi.Gote:
+		pos.Turn = shogi.Gote
+	default:
+		return nil, errors.New("unknown turn. input = " + fields[1])
+	}
+
+	if err := parseSFENHands(fields[2], pos); err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 4 {
+		mc, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("move count is not a number. input = %s: %w", fields[3], err)
+		}
+		pos.MoveCount = mc
+	}
+
+	return pos, nil
+}
+
+func parseSFENRow(s string) ([]int, error) {
+	row := make([]int, 0, 9)
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+
+		if ch >= '1' && ch <= '9' {
+			for j := 0; j < int(ch-'0'); j++ {
+				row = append(row, shogi.Empty.ToInt())
+			}
+			continue
+		}
+
+		p := string(ch)
+		if ch == '+' && i+1 < len(s) {
+			i++
+			p += string(s[i])
+		}
+		piece, err := Piece(usi.Piece(p))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse piece of sfen. input = %s: %w", p, err)
+		}
+		row = append(row, piece.ToInt())
+	}
+
+	if len(row) != 9 {
+		return nil, errors.New("the row of sfen must have 9 columns. input = " + s)
+	}
+	return row, nil
+}
+
+// parseSFENHands parses the pieces in hand, and sets them to the position.
+func parseSFENHands(s string, pos *shogi.Position) error {
+	if s == "-" {
+		return nil
+	}
+
+	n := 0
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= '0' && ch <= '9' {
+			n = n*10 + int(ch-'0')
+			continue
+		}
+
+		piece, err := Piece(usi.Piece(string(ch)))
+		if err != nil {
+			return fmt.Errorf("failed to parse piece in hand. input = %c: %w", ch, err)
+		}
+		if n == 0 {
+			n = 1
+		}
+
+		switch {
+		case piece >= shogi.Fu0 && piece <= shogi.Hisha0:
+			pos.Cap0[piece-1] += n
+		case piece <= shogi.Fu1 && piece >= shogi.Hisha1:
+			pos.Cap1[-piece-1] += n
+		default:
+			return fmt.Errorf("the piece cannot be in hand. input = %c", ch)
+		}
+		n = 0
+	}
+
+	if n != 0 {
+		return errors.New("the count of pieces in hand must be followed by a piece. input = " + s)
+	}
+	return nil
+}
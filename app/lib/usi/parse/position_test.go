@@ -0,0 +1,139 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+)
+
+func startPosition() *shogi.Position {
+	return &shogi.Position{
+		Pos: [][]int{
+			{-2, -3, -4, -5, -8, -5, -4, -3, -2},
+			{0, -7, 0, 0, 0, 0, 0, -6, 0},
+			{-1, -1, -1, -1, -1, -1, -1, -1, -1},
+			{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			{1, 1, 1, 1, 1, 1, 1, 1, 1},
+			{0, 6, 0, 0, 0, 0, 0, 7, 0},
+			{2, 3, 4, 5, 8, 5, 4, 3, 2},
+		},
+		Cap0:      []int{0, 0, 0, 0, 0, 0, 0},
+		Cap1:      []int{0, 0, 0, 0, 0, 0, 0},
+		Turn:      shogi.Sente,
+		MoveCount: 1,
+	}
+}
+
+func TestSFEN(t *testing.T) {
+	cases := []struct {
+		in   string
+		want *shogi.Position
+		err  bool
+	}{
+		{StartPos, startPosition(), false},
+		{
+			"lnsgk1snl/6gb1/p1pppp2p/6R2/9/1rP6/P2PPPP1P/1BG6/LNS1KGSNL w 3P2p 100",
+			&shogi.Position{
+				Pos: [][]int{
+					{-2, -3, -4, -5, -8, 0, -4, -3, -2},
+					{0, 0, 0, 0, 0, 0, -5, -6, 0},
+					{-1, 0, -1, -1, -1, -1, 0, 0, -1},
+					{0, 0, 0, 0, 0, 0, 7, 0, 0},
+					{0, 0, 0, 0, 0, 0, 0, 0, 0},
+					{0, -7, 1, 0, 0, 0, 0, 0, 0},
+					{1, 0, 0, 1, 1, 1, 1, 0, 1},
+					{0, 6, 5, 0, 0, 0, 0, 0, 0},
+					{2, 3, 4, 0, 8, 5, 4, 3, 2},
+				},
+				Cap0:      []int{3, 0, 0, 0, 0, 0, 0},
+				Cap1:      []int{2, 0, 0, 0, 0, 0, 0},
+				Turn:      shogi.Gote,
+				MoveCount: 100,
+			},
+			false,
+		},
+		// promoted pieces, many pieces in hand, and no move count
+		{
+			"8k/7+R1/9/9/9/9/9/+p8/K8 b R2B4G4S4N4L17p",
+			&shogi.Position{
+				Pos: [][]int{
+					{0, 0, 0, 0, 0, 0, 0, 0, -8},
+					{0, 0, 0, 0, 0, 0, 0, 17, 0},
+					{0, 0, 0, 0, 0, 0, 0, 0, 0},
+					{0, 0, 0, 0, 0, 0, 0, 0, 0},
+					{0, 0, 0, 0, 0, 0, 0, 0, 0},
+					{0, 0, 0, 0, 0, 0, 0, 0, 0},
+					{0, 0, 0, 0, 0, 0, 0, 0, 0},
+					{-11, 0, 0, 0, 0, 0, 0, 0, 0},
+					{8, 0, 0, 0, 0, 0, 0, 0, 0},
+				},
+				Cap0:      []int{0, 4, 4, 4, 4, 2, 1},
+				Cap1:      []int{17, 0, 0, 0, 0, 0, 0},
+				Turn:      shogi.Sente,
+				MoveCount: 1,
+			},
+			false,
+		},
+		{"", nil, true},
+		{"lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1 b - 1", nil, true},
+		{"lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSN b - 1", nil, true},
+		{"lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNLL b - 1", nil, true},
+		{"lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNX b - 1", nil, true},
+		{"lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL x - 1", nil, true},
+		{"lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b K 1", nil, true},
+		{"lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b 2 1", nil, true},
+		{"lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - x", nil, true},
+	}
+
+	for i, c := range cases {
+		res, err := SFEN(c.in)
+		if (err != nil) != c.err || !reflect.DeepEqual(res, c.want) {
+			t.Errorf(`[SFEN]
+Index:    %d
+Input:    %s
+Expected: %v, error=%v
+Actual:   %v, %v
+`, i, c.in, c.want, c.err, res, err)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	f76 := &shogi.Move{Source: &shogi.Point{Row: 6, Column: 6}, Dest: &shogi.Point{Row: 5, Column: 6}}
+	c34 := &shogi.Move{Source: &shogi.Point{Row: 2, Column: 2}, Dest: &shogi.Point{Row: 3, Column: 2}}
+
+	cases := []struct {
+		in    string
+		pos   *shogi.Position
+		moves []*shogi.Move
+		err   bool
+	}{
+		{"startpos", startPosition(), []*shogi.Move{}, false},
+		{"position startpos moves 7g7f 3c3d", startPosition(), []*shogi.Move{f76, c34}, false},
+		{"position sfen " + StartPos, startPosition(), []*shogi.Move{}, false},
+		{"sfen " + StartPos + " moves 7g7f", startPosition(), []*shogi.Move{f76}, false},
+		{" position  startpos  moves  7g7f \n", startPosition(), []*shogi.Move{f76}, false},
+		{"startpos moves", startPosition(), []*shogi.Move{}, false},
+		{"", nil, nil, true},
+		{"position", nil, nil, true},
+		{"position startpos 1", nil, nil, true},
+		{"position kifu " + StartPos, nil, nil, true},
+		{"position sfen moves 7g7f", nil, nil, true},
+		{"position startpos moves 7g7z", nil, nil, true},
+	}
+
+	for i, c := range cases {
+		pos, moves, err := Position(c.in)
+		if (err != nil) != c.err || !reflect.DeepEqual(pos, c.pos) || !reflect.DeepEqual(moves, c.moves) {
+			t.Errorf(`[Position]
+Index:    %d
+Input:    %s
+Expected: %v, %v, error=%v
+Actual:   %v, %v, %v
+`, i, c.in, c.pos, c.moves, c.err, pos, moves, err)
+		}
+	}
+}
@@ -16,8 +16,11 @@ var (
 		Engine:     store.NewEngineStore(),
 		EngineInfo: store.NewEngineInfoStore(),
 		Game:       store.NewGameStore(),
+		Transcript: store.NewTranscriptStore(),
 	}
 
+	Publisher = infrastructure.NewPublisher()
+
 	Services services
 )
 
@@ -26,6 +29,7 @@ type (
 		Engine     store.EngineStore
 		EngineInfo store.EngineInfoStore
 		Game       store.GameStore
+		Transcript store.TranscriptStore
 	}
 
 	services struct {
@@ -43,6 +47,8 @@ func Initialize(appConfigPath, logConfigPath string) {
 			Stores.Engine,
 			Stores.EngineInfo,
 			Stores.Game,
+			Stores.Transcript,
+			Publisher,
 			Config,
 			Logger,
 			infrastructure.NewCmd,
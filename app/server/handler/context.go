@@ -5,6 +5,7 @@
 package handler
 
 import (
+	"net/http"
 	"net/url"
 
 	"github.com/labstack/echo/v4"
@@ -36,3 +37,7 @@ func (ctx *Context) Text(status int, b []byte) error {
 }
 
 func (ctx *Context) JSON(status int, v interface{}) error { return ctx.ec.JSON(status, v) }
+
+func (ctx *Context) Request() *http.Request { return ctx.ec.Request() }
+
+func (ctx *Context) Response() http.ResponseWriter { return ctx.ec.Response() }
@@ -1,18 +1,26 @@
 package handlers
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
 	"github.com/murosan/shogi-board-server/app/domain/framework"
 	"github.com/murosan/shogi-board-server/app/server/handler"
 )
 
+// maxWait is the upper limit of the wait query.
+const maxWait = time.Minute
+
 // QueryKeys is a set of uri query keys.
 var QueryKeys = struct {
 	EngineID,
-	EngineIDAlias string
+	EngineIDAlias,
+	Wait string
 }{
 	EngineID:      "engine",
 	EngineIDAlias: "key", // for backward compatibility
+	Wait:          "wait",
 }
 
 // WithEngineID executes block with a engine.ID if the engine name is specified,
@@ -39,3 +47,29 @@ func GetEngineID(ctx *handler.Context) (engine.ID, error) {
 	errMsg := "please specify engine id in query parameter"
 	return "", framework.NewBadRequestError(errMsg, nil)
 }
+
+// ParseWait parses the wait query, and returns BAD_REQUEST error if invalid.
+// Both Go duration format (e.g. 30s) and the number of seconds are accepted.
+// Returns 0 when the query is empty. The duration is capped at one minute.
+func ParseWait(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		sec, e := strconv.Atoi(s)
+		if e != nil {
+			return 0, framework.NewBadRequestError("invalid wait. value="+s, err)
+		}
+		d = time.Duration(sec) * time.Second
+	}
+
+	if d < 0 {
+		return 0, framework.NewBadRequestError("wait must not be negative. value="+s, nil)
+	}
+	if d > maxWait {
+		d = maxWait
+	}
+	return d, nil
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// WebSocketHandler is a handler that streams events of the engine over WebSocket.
+// Each message is a JSON of event.Event, sent as soon as
+//   - the engine outputs info (with multipv index)
+//   - the engine state has changed
+//   - the position has changed
+// When the engine is closed, the closed event is sent and the socket is closed.
+// The socket is also closed when the client falls too far behind to receive
+// all events, then the client should reconnect and get the status again.
+// See domain/entity/event/event.go about events.
+type WebSocketHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewWebSocketHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &WebSocketHandler{es: es, logger: logger}
+}
+
+func (hdr *WebSocketHandler) Func(ctx *handler.Context) error {
+	id, err := handlers.GetEngineID(ctx)
+	if err != nil {
+		return err
+	}
+
+	events, unsubscribe, err := hdr.es.Subscribe(id)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	server := websocket.Server{Handshake: handshake}
+	server.Handler = func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		// the client sends nothing, so just waits for closing
+		closed := make(chan struct{})
+		go func() {
+			var msg string
+			for websocket.Message.Receive(ws, &msg) == nil {
+			}
+			close(closed)
+		}()
+
+		// the request is canceled on shutdown
+		done := ctx.Request().Context().Done()
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(ws, e); err != nil {
+					hdr.logger.Info("[WebSocket] send", zap.Error(err))
+					return
+				}
+			case <-closed:
+				return
+			case <-done:
+				return
+			}
+		}
+	}
+	server.ServeHTTP(ctx.Response(), ctx.Request())
+
+	return nil
+}
+
+// handshake accepts any origin, as the CORS of the server does.
+// Requests without Origin, which non-browser clients send, are accepted too.
+func handshake(config *websocket.Config, req *http.Request) (err error) {
+	config.Origin, err = websocket.Origin(config, req)
+	return err
+}
+
+func (*WebSocketHandler) Description() string {
+	return "" // TODO
+}
+
+func (*WebSocketHandler) Methods() []string {
+	return []string{
+		http.MethodGet,
+	}
+}
@@ -0,0 +1,66 @@
+package gametree
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
+	"github.com/murosan/shogi-board-server/app/domain/framework"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// CommentHandler is a handler for setting the comment to the node specified
+// by the node query. This handler requires the body as JSON like
+// {"comment":"..."}. An empty comment deletes it.
+// Returns the game tree as JSON.
+type CommentHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+// commentBody is the body of CommentHandler.
+type commentBody struct {
+	Comment string `json:"comment"`
+}
+
+func NewCommentHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &CommentHandler{es: es, logger: logger}
+}
+
+func (hdr *CommentHandler) Func(ctx *handler.Context) error {
+	node, err := getNodeID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var body commentBody
+	if err := ctx.Bind(&body); err != nil {
+		return framework.NewBadRequestError("body required", err)
+	}
+
+	var tree *game.Tree
+	err = handlers.WithEngineID(ctx, func(id engine.ID) error {
+		t, err := hdr.es.CommentNode(id, node, body.Comment)
+		tree = t
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, tree)
+}
+
+func (*CommentHandler) Description() string {
+	return "" // TODO
+}
+
+func (*CommentHandler) Methods() []string {
+	return []string{
+		http.MethodPost,
+	}
+}
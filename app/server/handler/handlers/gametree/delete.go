@@ -0,0 +1,54 @@
+package gametree
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// DeleteHandler is a handler for deleting the node specified by the node query,
+// and its descendants. If the current position is deleted, the parent of
+// the node becomes the current position. Returns the game tree as JSON.
+type DeleteHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewDeleteHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &DeleteHandler{es: es, logger: logger}
+}
+
+func (hdr *DeleteHandler) Func(ctx *handler.Context) error {
+	node, err := getNodeID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var tree *game.Tree
+	err = handlers.WithEngineID(ctx, func(id engine.ID) error {
+		t, err := hdr.es.DeleteNode(id, node)
+		tree = t
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, tree)
+}
+
+func (*DeleteHandler) Description() string {
+	return "" // TODO
+}
+
+func (*DeleteHandler) Methods() []string {
+	return []string{
+		http.MethodPost,
+	}
+}
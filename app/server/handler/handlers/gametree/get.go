@@ -0,0 +1,55 @@
+package gametree
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
+	"github.com/murosan/shogi-board-server/app/domain/framework"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// GetHandler is a handler for getting the game tree, which is the initial
+// position and the moves made from it with variations.
+// See domain/entity/game/tree.go.
+// Returns NOT_FOUND when the engine does not exists or the game has not started yet.
+type GetHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewGetHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &GetHandler{es: es, logger: logger}
+}
+
+func (hdr *GetHandler) Func(ctx *handler.Context) error {
+	var tree *game.Tree
+	var ok bool
+	err := handlers.WithEngineID(ctx, func(id engine.ID) error {
+		tree, ok = hdr.es.GetGame(id)
+		if !ok {
+			return framework.NewNotFoundError("game not found. id="+id.String(), nil)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, tree)
+}
+
+func (*GetHandler) Description() string {
+	return "" // TODO
+}
+
+func (*GetHandler) Methods() []string {
+	return []string{
+		http.MethodHead,
+		http.MethodGet,
+	}
+}
@@ -0,0 +1,26 @@
+package gametree
+
+import (
+	"strconv"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
+	"github.com/murosan/shogi-board-server/app/domain/framework"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+)
+
+var queryKeys = struct {
+	node string
+}{
+	node: "node",
+}
+
+// getNodeID gets the game.NodeID from the uri query and returns it,
+// otherwise returns BAD_REQUEST error.
+func getNodeID(ctx *handler.Context) (game.NodeID, error) {
+	q := ctx.GetQuery(queryKeys.node)
+	n, err := strconv.Atoi(q)
+	if err != nil {
+		return 0, framework.NewBadRequestError("please specify node query as a number. value="+q, err)
+	}
+	return game.NodeID(n), nil
+}
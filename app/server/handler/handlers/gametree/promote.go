@@ -0,0 +1,53 @@
+package gametree
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// PromoteHandler is a handler for making the node specified by the node query
+// the main line of its siblings. Returns the game tree as JSON.
+type PromoteHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewPromoteHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &PromoteHandler{es: es, logger: logger}
+}
+
+func (hdr *PromoteHandler) Func(ctx *handler.Context) error {
+	node, err := getNodeID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var tree *game.Tree
+	err = handlers.WithEngineID(ctx, func(id engine.ID) error {
+		t, err := hdr.es.PromoteNode(id, node)
+		tree = t
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, tree)
+}
+
+func (*PromoteHandler) Description() string {
+	return "" // TODO
+}
+
+func (*PromoteHandler) Methods() []string {
+	return []string{
+		http.MethodPost,
+	}
+}
@@ -0,0 +1,54 @@
+package gametree
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// SelectHandler is a handler for moving the current position to the node
+// specified by the node query. The engine follows the position.
+// Returns the new position as JSON.
+type SelectHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewSelectHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &SelectHandler{es: es, logger: logger}
+}
+
+func (hdr *SelectHandler) Func(ctx *handler.Context) error {
+	node, err := getNodeID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pos *shogi.Position
+	err = handlers.WithEngineID(ctx, func(id engine.ID) error {
+		p, err := hdr.es.SelectNode(id, node)
+		pos = p
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, pos)
+}
+
+func (*SelectHandler) Description() string {
+	return "" // TODO
+}
+
+func (*SelectHandler) Methods() []string {
+	return []string{
+		http.MethodPost,
+	}
+}
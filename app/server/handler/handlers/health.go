@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+)
+
+// HealthHandler is a handler for getting the result of the health checks
+// of the engine, including the latency of 'isready'.
+// See domain/entity/engine/health.go.
+type HealthHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewHealthHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &HealthHandler{es: es, logger: logger}
+}
+
+func (hdr *HealthHandler) Func(ctx *handler.Context) error {
+	id, err := GetEngineID(ctx)
+	if err != nil {
+		return err
+	}
+
+	health, err := hdr.es.GetHealth(id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, health)
+}
+
+func (*HealthHandler) Description() string {
+	return "" // TODO
+}
+
+func (*HealthHandler) Methods() []string {
+	return []string{
+		http.MethodHead,
+		http.MethodGet,
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -11,6 +12,9 @@ import (
 	"github.com/murosan/shogi-board-server/app/server/handler"
 )
 
+// closeAllTimeout is the max time to wait for each engine to exit.
+const closeAllTimeout = 10 * time.Second
+
 // InitHandler is a handler that initializes all engine.
 // It closes all engines and returns the list of engine names.
 type InitHandler struct {
@@ -28,7 +32,7 @@ func NewInitHandler(
 }
 
 func (hdr *InitHandler) Func(ctx *handler.Context) error {
-	if err := hdr.es.CloseAll(); err != nil {
+	if err := hdr.es.CloseAll(closeAllTimeout); err != nil {
 		return err
 	}
 
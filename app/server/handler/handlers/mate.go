@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+	"github.com/murosan/shogi-board-server/app/domain/framework"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+)
+
+// MateHandler is a handler for mate (tsume) search of the current position.
+// The timeout for the engine can be given as JSON body in milliseconds,
+// e.g. {"timeout": 10000}. Without body, searches infinitely.
+// Waits for the result for the duration of wait query (one minute at most),
+// and stops the search when it expires.
+// Returns the result as JSON. See domain/entity/usi/checkmate.go.
+type MateHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewMateHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &MateHandler{es: es, logger: logger}
+}
+
+type mateBody struct {
+	Timeout int `json:"timeout"`
+}
+
+func (hdr *MateHandler) Func(ctx *handler.Context) error {
+	var body mateBody
+	if err := ctx.Bind(&body); err != nil {
+		return framework.NewBadRequestError("invalid body", err)
+	}
+
+	wait, err := ParseWait(ctx.GetQuery(QueryKeys.Wait))
+	if err != nil {
+		return err
+	}
+	if wait == 0 {
+		wait = maxWait
+	}
+
+	var cm *usi.Checkmate
+	err = WithEngineID(ctx, func(id engine.ID) error {
+		cm, err = hdr.es.Mate(id, body.Timeout, wait)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, cm)
+}
+
+func (*MateHandler) Description() string {
+	return "" // TODO
+}
+
+func (*MateHandler) Methods() []string {
+	return []string{
+		http.MethodPost,
+	}
+}
@@ -18,9 +18,11 @@ import (
 
 var (
 	queryKeys = struct {
-		format string
+		format,
+		ply string
 	}{
 		format: "format",
+		ply:    "ply",
 	}
 
 	queryValues = struct {
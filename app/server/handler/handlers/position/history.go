@@ -0,0 +1,56 @@
+package position
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
+	"github.com/murosan/shogi-board-server/app/domain/framework"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// HistoryHandler is a handler for getting the game, which is the initial
+// position and the moves made from it. See domain/entity/shogi/game.go.
+// The moves are the line of the game tree through the current node,
+// without variations. Use /game/get for the whole tree.
+// Returns NOT_FOUND when the engine does not exists or the game has not started yet.
+type HistoryHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewHistoryHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &HistoryHandler{es: es, logger: logger}
+}
+
+func (hdr *HistoryHandler) Func(ctx *handler.Context) error {
+	var tree *game.Tree
+	var ok bool
+	err := handlers.WithEngineID(ctx, func(id engine.ID) error {
+		tree, ok = hdr.es.GetGame(id)
+		if !ok {
+			return framework.NewNotFoundError("game not found. id="+id.String(), nil)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, tree.Game())
+}
+
+func (*HistoryHandler) Description() string {
+	return "" // TODO
+}
+
+func (*HistoryHandler) Methods() []string {
+	return []string{
+		http.MethodHead,
+		http.MethodGet,
+	}
+}
@@ -0,0 +1,57 @@
+package position
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/framework"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// JumpHandler is a handler for moving the current position to the ply
+// of the game. The ply is the count of moves from the initial position,
+// and is specified by the ply query. Returns the new position as JSON.
+type JumpHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewJumpHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &JumpHandler{es: es, logger: logger}
+}
+
+func (hdr *JumpHandler) Func(ctx *handler.Context) error {
+	q := ctx.GetQuery(queryKeys.ply)
+	ply, err := strconv.Atoi(q)
+	if err != nil {
+		return framework.NewBadRequestError("please specify ply query as a number. value="+q, err)
+	}
+
+	var pos *shogi.Position
+	err = handlers.WithEngineID(ctx, func(id engine.ID) error {
+		p, err := hdr.es.Jump(id, ply)
+		pos = p
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, pos)
+}
+
+func (*JumpHandler) Description() string {
+	return "" // TODO
+}
+
+func (*JumpHandler) Methods() []string {
+	return []string{
+		http.MethodPost,
+	}
+}
@@ -0,0 +1,74 @@
+package position
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/framework"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/lib/usi/parse"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// MoveHandler is a handler for applying a move to the current position.
+// This handler requires the move body as JSON, either of the usi move
+// like {"usi":"7g7f"}, or shogi.Move. See domain/entity/shogi/move.go.
+// Returns the new position as JSON.
+type MoveHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+// moveBody is the body of MoveHandler.
+type moveBody struct {
+	USI string `json:"usi"`
+	shogi.Move
+}
+
+func NewMoveHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &MoveHandler{es: es, logger: logger}
+}
+
+func (hdr *MoveHandler) Func(ctx *handler.Context) error {
+	var body moveBody
+	if err := ctx.Bind(&body); err != nil {
+		return framework.NewBadRequestError("body required", err)
+	}
+
+	move := &body.Move
+	if body.USI != "" {
+		m, err := parse.Move(body.USI)
+		if err != nil {
+			return framework.NewBadRequestError("invalid usi move. usi="+body.USI, err)
+		}
+		move = m
+	} else if move.Source == nil || move.Dest == nil {
+		return framework.NewBadRequestError("usi or source and dest of the move required", nil)
+	}
+
+	var pos *shogi.Position
+	err := handlers.WithEngineID(ctx, func(id engine.ID) error {
+		p, err := hdr.es.ApplyMove(id, move)
+		pos = p
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, pos)
+}
+
+func (*MoveHandler) Description() string {
+	return "" // TODO
+}
+
+func (*MoveHandler) Methods() []string {
+	return []string{
+		http.MethodPost,
+	}
+}
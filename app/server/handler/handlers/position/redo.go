@@ -0,0 +1,48 @@
+package position
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// RedoHandler is a handler for redoing the last undone move of the game.
+// Returns the new position as JSON.
+type RedoHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewRedoHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &RedoHandler{es: es, logger: logger}
+}
+
+func (hdr *RedoHandler) Func(ctx *handler.Context) error {
+	var pos *shogi.Position
+	err := handlers.WithEngineID(ctx, func(id engine.ID) error {
+		p, err := hdr.es.Redo(id)
+		pos = p
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, pos)
+}
+
+func (*RedoHandler) Description() string {
+	return "" // TODO
+}
+
+func (*RedoHandler) Methods() []string {
+	return []string{
+		http.MethodPost,
+	}
+}
@@ -1,20 +1,34 @@
 package position
 
 import (
+	"bytes"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
 	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
 	"github.com/murosan/shogi-board-server/app/domain/framework"
 	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/lib/usi/parse"
 	"github.com/murosan/shogi-board-server/app/logger"
 	"github.com/murosan/shogi-board-server/app/server/handler"
 	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
 )
 
+// maxBodySize is the max size of the position body, which is
+// large enough for a game of a thousand moves.
+const maxBodySize = 1 << 20
+
 // SetHandler is a handler for setting the new position.
 // This handler requires the position body as JSON.
 // See domain/entity/shogi/position.go about position.
+//
+// The body can also be the usi position text, which is
+// 'sfen <sfen>' or 'startpos' followed by optional 'moves ...'.
+// The text is told from JSON by the body, not by Content-Type, because
+// clients like curl send text as application/x-www-form-urlencoded.
+// The moves become the main line of the new game.
 type SetHandler struct {
 	es     service.EngineService
 	logger logger.Logger
@@ -25,12 +39,25 @@ func NewSetHandler(es service.EngineService, logger logger.Logger) handler.Handl
 }
 
 func (hdr *SetHandler) Func(ctx *handler.Context) error {
+	req := ctx.Request()
+	b, err := ioutil.ReadAll(http.MaxBytesReader(ctx.Response(), req.Body, maxBodySize))
+	if err != nil {
+		return framework.NewBadRequestError("failed to read body", err)
+	}
+
+	if isPositionText(b) {
+		return hdr.setText(ctx, string(b))
+	}
+
+	// the body has been read, so give it to Bind again
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+
 	var pos shogi.Position
 	if err := ctx.Bind(&pos); err != nil {
 		return framework.NewBadRequestError("body required", err)
 	}
 
-	err := handlers.WithEngineID(ctx, func(id engine.ID) error {
+	err = handlers.WithEngineID(ctx, func(id engine.ID) error {
 		return hdr.es.UpdatePosition(id, &pos)
 	})
 
@@ -41,6 +68,38 @@ func (hdr *SetHandler) Func(ctx *handler.Context) error {
 	return ctx.NoContent(http.StatusOK)
 }
 
+// isPositionText returns true if the body is the usi position text.
+func isPositionText(b []byte) bool {
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "position", "sfen", "startpos":
+		return true
+	}
+	return false
+}
+
+// setText sets the position of the usi position text.
+func (hdr *SetHandler) setText(ctx *handler.Context, text string) error {
+	pos, moves, err := parse.Position(text)
+	if err != nil {
+		return framework.NewBadRequestError("invalid position. body="+text, err)
+	}
+
+	err = handlers.WithEngineID(ctx, func(id engine.ID) error {
+		_, err := hdr.es.SetGame(id, pos, moves)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.NoContent(http.StatusOK)
+}
+
 func (*SetHandler) Description() string {
 	return "" // TODO
 }
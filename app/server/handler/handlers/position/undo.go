@@ -0,0 +1,48 @@
+package position
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// UndoHandler is a handler for undoing the last move of the game.
+// Returns the new position as JSON.
+type UndoHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewUndoHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &UndoHandler{es: es, logger: logger}
+}
+
+func (hdr *UndoHandler) Func(ctx *handler.Context) error {
+	var pos *shogi.Position
+	err := handlers.WithEngineID(ctx, func(id engine.ID) error {
+		p, err := hdr.es.Undo(id)
+		pos = p
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, pos)
+}
+
+func (*UndoHandler) Description() string {
+	return "" // TODO
+}
+
+func (*UndoHandler) Methods() []string {
+	return []string{
+		http.MethodPost,
+	}
+}
@@ -0,0 +1,47 @@
+package result
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/framework"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// BestMoveHandler is a handler for getting the last best move of the engine.
+// Returns NOT_FOUND when the engine has not output bestmove since the search started.
+type BestMoveHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewBestMoveHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &BestMoveHandler{es: es, logger: logger}
+}
+
+func (hdr *BestMoveHandler) Func(ctx *handler.Context) error {
+	id, err := handlers.GetEngineID(ctx)
+	if err != nil {
+		return err
+	}
+
+	bm, ok := hdr.es.GetBestMove(id)
+	if !ok {
+		return framework.NewNotFoundError("bestmove not found. id="+id.String(), nil)
+	}
+
+	return ctx.JSON(http.StatusOK, bm)
+}
+
+func (*BestMoveHandler) Description() string {
+	return "" // TODO
+}
+
+func (*BestMoveHandler) Methods() []string {
+	return []string{
+		http.MethodHead,
+		http.MethodGet,
+	}
+}
@@ -1,7 +1,10 @@
 package result
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/murosan/shogi-board-server/app/domain/service"
 	"github.com/murosan/shogi-board-server/app/logger"
@@ -9,7 +12,18 @@ import (
 	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
 )
 
+const (
+	etagHeader        = "ETag"
+	ifNoneMatchHeader = "If-None-Match"
+)
+
 // GetHandler is a handler for getting thought result of the engine.
+// The revision of the result is returned as ETag.
+// When If-None-Match is given and it matches the current revision,
+// waits until the result changes for the duration specified by the
+// wait query (e.g. wait=30s or wait=30), then returns NOT_MODIFIED
+// if still nothing has changed. If-None-Match can be a list of ETags,
+// or '*' which matches any revision.
 type GetHandler struct {
 	es     service.EngineService
 	logger logger.Logger
@@ -25,7 +39,19 @@ func (hdr *GetHandler) Func(ctx *handler.Context) error {
 		return err
 	}
 
-	return ctx.JSON(http.StatusOK, hdr.es.GetResult(id))
+	wait, err := handlers.ParseWait(ctx.GetQuery(handlers.QueryKeys.Wait))
+	if err != nil {
+		return err
+	}
+
+	match := parseIfNoneMatch(ctx.Request().Header.Get(ifNoneMatchHeader))
+	res, rev := hdr.es.WaitResult(ctx.Request().Context(), id, match, wait)
+	ctx.Response().Header().Set(etagHeader, formatETag(rev))
+
+	if match(rev) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	return ctx.JSON(http.StatusOK, res)
 }
 
 func (*GetHandler) Description() string {
@@ -38,3 +64,34 @@ func (*GetHandler) Methods() []string {
 		http.MethodGet,
 	}
 }
+
+func formatETag(rev uint64) string { return fmt.Sprintf(`"%d"`, rev) }
+
+// parseIfNoneMatch returns a function which reports whether the revision
+// matches one of the ETags of If-None-Match.
+func parseIfNoneMatch(s string) func(uint64) bool {
+	if strings.TrimSpace(s) == "*" {
+		return func(uint64) bool { return true }
+	}
+
+	revs := make(map[uint64]bool)
+	for _, etag := range strings.Split(s, ",") {
+		if rev, ok := parseETag(etag); ok {
+			revs[rev] = true
+		}
+	}
+	return func(rev uint64) bool { return revs[rev] }
+}
+
+func parseETag(s string) (uint64, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "W/")
+	s = strings.Trim(s, `"`)
+	if s == "" {
+		return 0, false
+	}
+	rev, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false // never matches
+	}
+	return rev, true
+}
@@ -0,0 +1,148 @@
+package result
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/murosan/shogi-board-server/app/domain/entity/event"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+	"github.com/murosan/shogi-board-server/app/domain/framework"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+	heartbeatInterval = 15 * time.Second
+)
+
+// StreamHandler is a handler that streams changes of the thought result
+// of the engine as Server-Sent Events.
+// Each event has the change ID as its id, and the change type
+// (upsert, delete or snapshot) as its event name.
+// A reconnecting client can resume by sending Last-Event-ID header.
+// The stream ends when the client falls too far behind to receive all
+// changes or messages, then the client should reconnect to resume. It also ends
+// when the engine is closed.
+// 'info string' messages are also sent as message events without id,
+// and they are not resumed.
+// See domain/entity/usi/result.go about changes.
+type StreamHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewStreamHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &StreamHandler{es: es, logger: logger}
+}
+
+func (hdr *StreamHandler) Func(ctx *handler.Context) error {
+	id, err := handlers.GetEngineID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var lastID uint64
+	if v := ctx.Request().Header.Get(lastEventIDHeader); v != "" {
+		lastID, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return framework.NewBadRequestError("invalid "+lastEventIDHeader, err)
+		}
+	}
+
+	w := ctx.Response()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return framework.NewInternalServerError("streaming is not supported", nil)
+	}
+
+	changes, ch, unwatch, err := hdr.es.WatchResult(id, lastID)
+	if err != nil {
+		return err
+	}
+	defer unwatch()
+
+	events, unsubscribe, err := hdr.es.Subscribe(id)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, c := range changes {
+		if err := writeEvent(w, c); err != nil {
+			return nil // client has gone
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	done := ctx.Request().Context().Done()
+	for {
+		select {
+		case c, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(w, c); err != nil {
+				return nil
+			}
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if e.Type != event.Message {
+				continue
+			}
+			if err := writeMessage(w, e.Message); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		case <-done:
+			return nil
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, c *usi.ResultChange) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", c.ID, c.Type, b)
+	return err
+}
+
+func writeMessage(w http.ResponseWriter, m *usi.Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Message, b)
+	return err
+}
+
+func (*StreamHandler) Description() string {
+	return "" // TODO
+}
+
+func (*StreamHandler) Methods() []string {
+	return []string{
+		http.MethodGet,
+	}
+}
@@ -3,6 +3,9 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+	"github.com/murosan/shogi-board-server/app/domain/framework"
 	"github.com/murosan/shogi-board-server/app/domain/service"
 	"github.com/murosan/shogi-board-server/app/logger"
 	"github.com/murosan/shogi-board-server/app/server/handler"
@@ -13,7 +16,10 @@ import (
 //   - NotConnected, then returns NOT_FOUND or BAD_REQUEST
 //   - Connected or StandBy, then starts thinking and returns OK
 //   - Thinking, then returns OK (do nothing)
-// See domain/entity/engine/state.go about engine state.
+// The search limits can be given as JSON body. Without body, thinks infinitely.
+// The state goes back to StandBy when the engine finishes the search by limits.
+// See domain/entity/engine/state.go about engine state,
+// and domain/entity/usi/search.go about search limits.
 type StartHandler struct {
 	es     service.EngineService
 	logger logger.Logger
@@ -24,7 +30,15 @@ func NewStartHandler(es service.EngineService, logger logger.Logger) handler.Han
 }
 
 func (hdr *StartHandler) Func(ctx *handler.Context) error {
-	if err := WithEngineID(ctx, hdr.es.Start); err != nil {
+	var limit usi.SearchLimit
+	if err := ctx.Bind(&limit); err != nil {
+		return framework.NewBadRequestError("invalid body", err)
+	}
+
+	err := WithEngineID(ctx, func(id engine.ID) error {
+		return hdr.es.Start(id, &limit)
+	})
+	if err != nil {
 		return err
 	}
 	return ctx.NoContent(http.StatusOK)
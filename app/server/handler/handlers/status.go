@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+)
+
+// StatusHandler is a handler for getting the status of the engine,
+// including the last crash and the count of restarts.
+// See domain/entity/engine/status.go.
+type StatusHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewStatusHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &StatusHandler{es: es, logger: logger}
+}
+
+func (hdr *StatusHandler) Func(ctx *handler.Context) error {
+	id, err := GetEngineID(ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := hdr.es.GetStatus(id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, status)
+}
+
+func (*StatusHandler) Description() string {
+	return "" // TODO
+}
+
+func (*StatusHandler) Methods() []string {
+	return []string{
+		http.MethodHead,
+		http.MethodGet,
+	}
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
 	"github.com/murosan/shogi-board-server/app/domain/service"
 	"github.com/murosan/shogi-board-server/app/logger"
 	"github.com/murosan/shogi-board-server/app/server/handler"
@@ -13,6 +15,8 @@ import (
 //   - NotConnected, then returns NOT_FOUND or BAD_REQUEST
 //   - Connected or StandBy, then returns OK (do nothing)
 //   - Thinking, then stops thinking and returns OK
+// When the wait query is given (e.g. wait=5s), waits for the engine's
+// best move and returns it as JSON.
 // See domain/entity/engine/state.go about engine state.
 type StopHandler struct {
 	es     service.EngineService
@@ -24,10 +28,27 @@ func NewStopHandler(es service.EngineService, logger logger.Logger) handler.Hand
 }
 
 func (hdr *StopHandler) Func(ctx *handler.Context) error {
-	if err := WithEngineID(ctx, hdr.es.Stop); err != nil {
+	wait, err := ParseWait(ctx.GetQuery(QueryKeys.Wait))
+	if err != nil {
 		return err
 	}
-	return ctx.NoContent(http.StatusOK)
+
+	if wait == 0 {
+		if err := WithEngineID(ctx, hdr.es.Stop); err != nil {
+			return err
+		}
+		return ctx.NoContent(http.StatusOK)
+	}
+
+	var bm *usi.BestMove
+	err = WithEngineID(ctx, func(id engine.ID) error {
+		bm, err = hdr.es.StopAndWait(id, wait)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, bm)
 }
 
 func (*StopHandler) Description() string {
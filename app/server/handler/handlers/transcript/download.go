@@ -0,0 +1,52 @@
+package transcript
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/murosan/shogi-board-server/app/domain/service"
+	"github.com/murosan/shogi-board-server/app/logger"
+	"github.com/murosan/shogi-board-server/app/server/handler"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+)
+
+// DownloadHandler is a handler for downloading the transcript as a text file.
+// The file can be replayed with infrastructure.NewReplayCmd.
+type DownloadHandler struct {
+	es     service.EngineService
+	logger logger.Logger
+}
+
+func NewDownloadHandler(es service.EngineService, logger logger.Logger) handler.Handler {
+	return &DownloadHandler{es: es, logger: logger}
+}
+
+func (hdr *DownloadHandler) Func(ctx *handler.Context) error {
+	id, err := handlers.GetEngineID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	for _, l := range hdr.es.GetTranscript(id) {
+		b.WriteString(l.String())
+		b.WriteByte('\n')
+	}
+
+	ctx.Response().Header().Set(
+		"Content-Disposition",
+		`attachment; filename="transcript-`+id.String()+`.txt"`,
+	)
+	return ctx.Text(http.StatusOK, b.Bytes())
+}
+
+func (*DownloadHandler) Description() string {
+	return "" // TODO
+}
+
+func (*DownloadHandler) Methods() []string {
+	return []string{
+		http.MethodHead,
+		http.MethodGet,
+	}
+}
@@ -8,10 +8,14 @@ import (
 	"github.com/murosan/shogi-board-server/app/logger"
 	"github.com/murosan/shogi-board-server/app/server/handler"
 	"github.com/murosan/shogi-board-server/app/server/handler/handlers"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers/events"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers/gametree"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers/messages"
 	"github.com/murosan/shogi-board-server/app/server/handler/handlers/options"
 	"github.com/murosan/shogi-board-server/app/server/handler/handlers/options/update"
 	"github.com/murosan/shogi-board-server/app/server/handler/handlers/position"
 	"github.com/murosan/shogi-board-server/app/server/handler/handlers/result"
+	"github.com/murosan/shogi-board-server/app/server/handler/handlers/transcript"
 )
 
 // Initialize setups server routes.
@@ -27,7 +31,10 @@ func Initialize(
 		{path: "/connect", handler: handlers.NewConnectHandler(es, logger)},
 		{path: "/close", handler: handlers.NewCloseHandler(es, logger)},
 		{path: "/start", handler: handlers.NewStartHandler(es, logger)},
+		{path: "/mate", handler: handlers.NewMateHandler(es, logger)},
 		{path: "/stop", handler: handlers.NewStopHandler(es, logger)},
+		{path: "/status", handler: handlers.NewStatusHandler(es, logger)},
+		{path: "/health", handler: handlers.NewHealthHandler(es, logger)},
 		{path: "/options/get", handler: options.NewGetHandler(es, logger)},
 		{path: "/options/update/button", handler: update.NewButtonHandler(es, logger)},
 		{path: "/options/update/check", handler: update.NewCheckHandler(es, logger)},
@@ -35,8 +42,24 @@ func Initialize(
 		{path: "/options/update/select", handler: update.NewSelectHandler(es, logger)},
 		{path: "/options/update/text", handler: update.NewTextHandler(es, logger)},
 		{path: "/result/get", handler: result.NewGetHandler(es, logger)},
+		{path: "/result/bestmove", handler: result.NewBestMoveHandler(es, logger)},
+		{path: "/result/stream", handler: result.NewStreamHandler(es, logger)},
 		{path: "/position/get", handler: position.NewGetHandler(es, logger)},
 		{path: "/position/set", handler: position.NewSetHandler(es, logger)},
+		{path: "/position/move", handler: position.NewMoveHandler(es, logger)},
+		{path: "/position/undo", handler: position.NewUndoHandler(es, logger)},
+		{path: "/position/redo", handler: position.NewRedoHandler(es, logger)},
+		{path: "/position/jump", handler: position.NewJumpHandler(es, logger)},
+		{path: "/position/history", handler: position.NewHistoryHandler(es, logger)},
+		{path: "/game/get", handler: gametree.NewGetHandler(es, logger)},
+		{path: "/game/select", handler: gametree.NewSelectHandler(es, logger)},
+		{path: "/game/delete", handler: gametree.NewDeleteHandler(es, logger)},
+		{path: "/game/promote", handler: gametree.NewPromoteHandler(es, logger)},
+		{path: "/game/comment", handler: gametree.NewCommentHandler(es, logger)},
+		{path: "/messages/get", handler: messages.NewGetHandler(es, logger)},
+		{path: "/transcript/get", handler: transcript.NewGetHandler(es, logger)},
+		{path: "/transcript/download", handler: transcript.NewDownloadHandler(es, logger)},
+		{path: "/events/ws", handler: events.NewWebSocketHandler(es, logger)},
 	}
 
 	for _, r := range routes {
@@ -0,0 +1,540 @@
+package routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/murosan/shogi-board-server/app/domain/config"
+	"github.com/murosan/shogi-board-server/app/domain/entity/engine"
+	"github.com/murosan/shogi-board-server/app/domain/entity/event"
+	"github.com/murosan/shogi-board-server/app/domain/entity/game"
+	"github.com/murosan/shogi-board-server/app/domain/entity/shogi"
+	"github.com/murosan/shogi-board-server/app/domain/entity/usi"
+	"github.com/murosan/shogi-board-server/app/domain/infrastructure"
+	"github.com/murosan/shogi-board-server/app/domain/infrastructure/fake"
+	"github.com/murosan/shogi-board-server/app/domain/infrastructure/store"
+	"github.com/murosan/shogi-board-server/app/domain/service"
+)
+
+const initialPosition = `{
+  "pos": [
+    [-2, -3, -4, -5, -8, -5, -4, -3, -2],
+    [0, -7, 0, 0, 0, 0, 0, -6, 0],
+    [-1, -1, -1, -1, -1, -1, -1, -1, -1],
+    [0, 0, 0, 0, 0, 0, 0, 0, 0],
+    [0, 0, 0, 0, 0, 0, 0, 0, 0],
+    [0, 0, 0, 0, 0, 0, 0, 0, 0],
+    [1, 1, 1, 1, 1, 1, 1, 1, 1],
+    [0, 6, 0, 0, 0, 0, 0, 7, 0],
+    [2, 3, 4, 5, 8, 5, 4, 3, 2]
+  ],
+  "cap0": [0, 0, 0, 0, 0, 0, 0],
+  "cap1": [0, 0, 0, 0, 0, 0, 0],
+  "turn": 1,
+  "moveCount": 1
+}`
+
+// newTestServer starts the server with the fake engine named 'fake'.
+func newTestServer(t *testing.T) (*httptest.Server, *fake.Cmd) {
+	t.Helper()
+
+	logger := zap.NewNop()
+	conf := &config.Config{
+		App: config.App{Engines: map[string]string{"fake": "/path/to/fake"}},
+	}
+
+	cmd := fake.NewCmd(&fake.Script{
+		Name:      "fake engine",
+		Options:   []string{"name USI_Ponder type check default false"},
+		Infos:     []string{"depth 1 score cp 10 pv 7g7f", "depth 2 score cp 20 pv 7g7f 3c3d"},
+		BestMove:  "7g7f",
+		Checkmate: "nomate",
+	})
+
+	es := service.NewEngineService(
+		store.NewEngineStore(),
+		store.NewEngineInfoStore(),
+		store.NewGameStore(),
+		store.NewTranscriptStore(),
+		infrastructure.NewPublisher(),
+		conf,
+		logger,
+		func(string) infrastructure.Cmd { return cmd },
+		infrastructure.NewConnector,
+	)
+
+	e := echo.New()
+	Initialize(e, conf, logger, es)
+	return httptest.NewServer(e), cmd
+}
+
+// request sends the request, and returns the status code and the body.
+// The body is sent as JSON if it starts with '{', otherwise as plain text.
+func request(t *testing.T, server *httptest.Server, method, path, body string) (int, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(body, "{") {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	} else if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMETextPlain)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, b
+}
+
+func TestRoutes_EngineFlow(t *testing.T) {
+	server, cmd := newTestServer(t)
+	defer server.Close()
+
+	expectStatus := func(want int, method, path, body string) []byte {
+		t.Helper()
+		status, b := request(t, server, method, path, body)
+		if status != want {
+			t.Fatalf(`
+[app > server > handler > routes] %s %s
+Expected: %d
+Actual:   %d %s
+`, method, path, want, status, string(b))
+		}
+		return b
+	}
+
+	expectStatus(http.StatusBadRequest, http.MethodPost, "/connect", "")
+	expectStatus(http.StatusNotFound, http.MethodPost, "/connect?engine=unknown", "")
+	expectStatus(http.StatusOK, http.MethodPost, "/connect?engine=fake", "")
+
+	var options engine.Options
+	b := expectStatus(http.StatusOK, http.MethodGet, "/options/get?engine=fake", "")
+	if err := json.Unmarshal(b, &options); err != nil || options.Checks["USI_Ponder"] == nil {
+		t.Errorf("[routes] unexpected options. body=%s, err=%v", string(b), err)
+	}
+
+	expectStatus(
+		http.StatusOK,
+		http.MethodPost,
+		"/options/update/check?engine=fake",
+		`{"name": "USI_Ponder", "value": true}`,
+	)
+	expectStatus(http.StatusOK, http.MethodPost, "/position/set?engine=fake", initialPosition)
+	expectStatus(http.StatusOK, http.MethodPost, "/start?engine=fake", `{}`)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		var res usi.Result
+		b := expectStatus(http.StatusOK, http.MethodGet, "/result/get?engine=fake", "")
+		if err := json.Unmarshal(b, &res); err != nil {
+			t.Fatal(err)
+		}
+		if len(res) != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("[routes] timeout waiting for the result")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var bm usi.BestMove
+	b = expectStatus(http.StatusOK, http.MethodPost, "/stop?engine=fake&wait=1s", "")
+	if err := json.Unmarshal(b, &bm); err != nil || bm.Move == nil {
+		t.Errorf("[routes] unexpected bestmove. body=%s, err=%v", string(b), err)
+	}
+
+	var status struct {
+		Name  string `json:"name"`
+		State string `json:"state"`
+	}
+	b = expectStatus(http.StatusOK, http.MethodGet, "/status?engine=fake", "")
+	if err := json.Unmarshal(b, &status); err != nil || status.State != "standBy" {
+		t.Errorf("[routes] unexpected status. body=%s, err=%v", string(b), err)
+	}
+
+	var pos shogi.Position
+	b = expectStatus(http.StatusOK, http.MethodPost, "/position/move?engine=fake", `{"usi": "7g7f"}`)
+	if err := json.Unmarshal(b, &pos); err != nil || pos.Turn != shogi.Gote || pos.Pos[5][2] != 1 {
+		t.Errorf("[routes] unexpected position. body=%s, err=%v", string(b), err)
+	}
+	expectStatus(
+		http.StatusOK,
+		http.MethodPost,
+		"/position/move?engine=fake",
+		`{"source": {"row": 2, "column": 2}, "dest": {"row": 3, "column": 2}}`,
+	)
+	expectStatus(http.StatusBadRequest, http.MethodPost, "/position/move?engine=fake", `{"usi": "7f7d"}`)
+	expectStatus(http.StatusBadRequest, http.MethodPost, "/position/move?engine=fake", `{}`)
+	expectStatus(http.StatusOK, http.MethodPost, "/position/undo?engine=fake", "")
+	expectStatus(http.StatusBadRequest, http.MethodPost, "/position/jump?engine=fake&ply=3", "")
+	expectStatus(http.StatusOK, http.MethodPost, "/position/jump?engine=fake&ply=0", "")
+	expectStatus(http.StatusOK, http.MethodPost, "/position/redo?engine=fake", "")
+
+	var tree game.Tree
+	b = expectStatus(http.StatusOK, http.MethodGet, "/game/get?engine=fake", "")
+	if err := json.Unmarshal(b, &tree); err != nil || len(tree.Line()) != 3 || tree.Current != 1 {
+		t.Errorf("[routes] unexpected game. body=%s, err=%v", string(b), err)
+	}
+	var history shogi.Game
+	b = expectStatus(http.StatusOK, http.MethodGet, "/position/history?engine=fake", "")
+	if err := json.Unmarshal(b, &history); err != nil || len(history.Moves) != 2 || history.Ply != 1 {
+		t.Errorf("[routes] unexpected history. body=%s, err=%v", string(b), err)
+	}
+	expectStatus(http.StatusOK, http.MethodPost, "/game/comment?engine=fake&node=2", `{"comment": "good"}`)
+	expectStatus(http.StatusNotFound, http.MethodPost, "/game/select?engine=fake&node=100", "")
+	expectStatus(http.StatusBadRequest, http.MethodPost, "/game/delete?engine=fake&node=0", "")
+	expectStatus(http.StatusOK, http.MethodPost, "/game/select?engine=fake&node=2", "")
+
+	expectStatus(http.StatusBadRequest, http.MethodPost, "/position/set?engine=fake", "position startpos moves 7g7d")
+	expectStatus(http.StatusBadRequest, http.MethodPost, "/position/set?engine=fake", "sfen 9/9 b - 1")
+	expectStatus(http.StatusOK, http.MethodPost, "/position/set?engine=fake", "position startpos moves 7g7f 8c8d")
+	b = expectStatus(http.StatusOK, http.MethodGet, "/game/get?engine=fake", "")
+	if err := json.Unmarshal(b, &tree); err != nil || len(tree.Line()) != 3 || len(tree.Path(tree.Current)) != 3 {
+		t.Errorf("[routes] unexpected game after setting text position. body=%s, err=%v", string(b), err)
+	}
+
+	expectStatus(http.StatusOK, http.MethodPost, "/close?engine=fake", "")
+	expectStatus(http.StatusNotFound, http.MethodGet, "/status?engine=fake", "")
+
+	want := []string{
+		"usi",
+		"isready",
+		"setoption name USI_Ponder value true",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1",
+		"usinewgame",
+		"go infinite",
+		"stop",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1 moves 7g7f",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1 moves 7g7f 3c3d",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1 moves 7g7f",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1 moves 7g7f",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1 moves 7g7f 3c3d",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1 moves 7g7f 8c8d",
+		"quit",
+	}
+	if r := cmd.Received(); strings.Join(r, "\n") != strings.Join(want, "\n") {
+		t.Errorf(`
+[app > server > handler > routes] unexpected commands
+Expected: %v
+Actual:   %v
+`, want, r)
+	}
+}
+
+func TestRoutes_EventsWebSocket(t *testing.T) {
+	server, _ := newTestServer(t)
+	defer server.Close()
+
+	mustRequest := func(method, path, body string) {
+		t.Helper()
+		if status, b := request(t, server, method, path, body); status != http.StatusOK {
+			t.Fatalf("[routes] %s %s: unexpected status %d %s", method, path, status, string(b))
+		}
+	}
+
+	mustRequest(http.MethodPost, "/connect?engine=fake", "")
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/events/ws?engine=fake"
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	// the fields of event.Event to check. State is the name of engine.State.
+	type received struct {
+		Type     event.Type      `json:"type"`
+		Info     *usi.Info       `json:"info"`
+		BestMove *usi.BestMove   `json:"bestmove"`
+		State    string          `json:"state"`
+		Position *shogi.Position `json:"position"`
+		Reason   string          `json:"reason"`
+	}
+
+	// receive waits for the event of the type, skipping the others
+	receive := func(typ event.Type) *received {
+		t.Helper()
+		_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+		for {
+			var e received
+			if err := websocket.JSON.Receive(ws, &e); err != nil {
+				t.Fatalf("[routes] waiting for %s event: %v", typ, err)
+			}
+			if e.Type == typ {
+				return &e
+			}
+		}
+	}
+
+	mustRequest(http.MethodPost, "/position/set?engine=fake", initialPosition)
+	if e := receive(event.Position); e.Position == nil || e.Position.Turn != shogi.Sente {
+		t.Errorf("[routes] unexpected position event: %v", e)
+	}
+
+	mustRequest(http.MethodPost, "/start?engine=fake", `{}`)
+	// standBy on usinewgame, and then thinking
+	for e := receive(event.State); e.State != "thinking"; e = receive(event.State) {
+	}
+	if e := receive(event.Info); e.Info == nil || len(e.Info.Moves) == 0 {
+		t.Errorf("[routes] unexpected info event: %v", e)
+	}
+
+	mustRequest(http.MethodPost, "/stop?engine=fake", "")
+	if e := receive(event.BestMove); e.BestMove == nil {
+		t.Errorf("[routes] unexpected bestmove event: %v", e)
+	}
+
+	// the socket is closed after the closed event
+	mustRequest(http.MethodPost, "/close?engine=fake", "")
+	if e := receive(event.Closed); e.Reason != event.ReasonRequested {
+		t.Errorf("[routes] unexpected closed event: %v", e)
+	}
+	var msg string
+	if err := websocket.Message.Receive(ws, &msg); err == nil {
+		t.Errorf("[routes] the socket was not closed. received=%s", msg)
+	}
+}
+
+func TestRoutes_ResultStream_Close(t *testing.T) {
+	server, _ := newTestServer(t)
+	defer server.Close()
+
+	if status, b := request(t, server, http.MethodPost, "/connect?engine=fake", ""); status != http.StatusOK {
+		t.Fatalf("[routes] connect: unexpected status %d %s", status, string(b))
+	}
+
+	res, err := http.Get(server.URL + "/result/stream?engine=fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	// the stream ends after the engine is closed
+	done := make(chan []byte)
+	go func() {
+		b, _ := ioutil.ReadAll(res.Body)
+		done <- b
+	}()
+	if status, b := request(t, server, http.MethodPost, "/close?engine=fake", ""); status != http.StatusOK {
+		t.Fatalf("[routes] close: unexpected status %d %s", status, string(b))
+	}
+
+	select {
+	case b := <-done:
+		if !strings.Contains(string(b), "event: snapshot") {
+			t.Errorf("[routes] unexpected stream: %s", string(b))
+		}
+	case <-time.After(time.Second):
+		t.Error("[routes] the stream did not end after the engine was closed")
+	}
+}
+
+func TestRoutes_EventsWebSocket_NoOrigin(t *testing.T) {
+	server, _ := newTestServer(t)
+	defer server.Close()
+
+	if status, b := request(t, server, http.MethodPost, "/connect?engine=fake", ""); status != http.StatusOK {
+		t.Fatalf("[routes] connect: unexpected status %d %s", status, string(b))
+	}
+
+	// the handshake of a non-browser client, which sends no Origin
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/events/ws?engine=fake", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("[routes] expected %d for the client without Origin, but got %d",
+			http.StatusSwitchingProtocols, res.StatusCode)
+	}
+}
+
+func TestRoutes_ResultETag(t *testing.T) {
+	server, _ := newTestServer(t)
+	defer server.Close()
+
+	if status, b := request(t, server, http.MethodPost, "/connect?engine=fake", ""); status != http.StatusOK {
+		t.Fatalf("[routes] connect: unexpected status %d %s", status, string(b))
+	}
+	if status, b := request(t, server, http.MethodPost, "/position/set?engine=fake", initialPosition); status != http.StatusOK {
+		t.Fatalf("[routes] set position: unexpected status %d %s", status, string(b))
+	}
+
+	// get sends /result/get with If-None-Match, and returns the status and ETag
+	get := func(query, ifNoneMatch string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/result/get?engine=fake"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode, res.Header.Get("ETag")
+	}
+
+	_, etag := get("", "")
+	if etag == "" {
+		t.Fatal("[routes] ETag is not returned")
+	}
+
+	cases := []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"100000", ` + etag, http.StatusNotModified},
+		{`"100000"`, http.StatusOK},
+		{`"100000", "100001"`, http.StatusOK},
+		{"invalid", http.StatusOK},
+	}
+
+	for i, c := range cases {
+		if status, _ := get("", c.ifNoneMatch); status != c.status {
+			t.Errorf(`
+[app > server > handler > routes] /result/get
+Index:       %d
+IfNoneMatch: %s
+Expected:    %d
+Actual:      %d
+`, i, c.ifNoneMatch, c.status, status)
+		}
+	}
+
+	// NOT_MODIFIED after waiting when nothing changes
+	start := time.Now()
+	if status, _ := get("&wait=100ms", etag); status != http.StatusNotModified || time.Since(start) < 100*time.Millisecond {
+		t.Errorf("[routes] /result/get with wait: expected %d after waiting, but got %d in %v",
+			http.StatusNotModified, status, time.Since(start))
+	}
+
+	// the waiting request returns as soon as the result changes
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		res, err := http.Post(server.URL+"/start?engine=fake", echo.MIMEApplicationJSON, strings.NewReader(`{}`))
+		if err == nil {
+			res.Body.Close()
+		}
+	}()
+	start = time.Now()
+	status, next := get("&wait=10s", etag)
+	if status != http.StatusOK || next == etag || time.Since(start) > 5*time.Second {
+		t.Errorf("[routes] /result/get with wait: expected %d with new ETag, but got %d %s in %v",
+			http.StatusOK, status, next, time.Since(start))
+	}
+
+	request(t, server, http.MethodPost, "/close?engine=fake", "")
+}
+
+func TestRoutes_PositionSet(t *testing.T) {
+	server, cmd := newTestServer(t)
+	defer server.Close()
+
+	if status, b := request(t, server, http.MethodPost, "/connect?engine=fake", ""); status != http.StatusOK {
+		t.Fatalf("[routes] connect: unexpected status %d %s", status, string(b))
+	}
+
+	// post sends the body with the content type, as curl -d does
+	post := func(contentType, body string) (int, string) {
+		t.Helper()
+		res, err := http.Post(server.URL+"/position/set?engine=fake", contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, string(b)
+	}
+
+	cases := []struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		{echo.MIMEApplicationForm, "position startpos moves 7g7f", http.StatusOK},
+		{echo.MIMEApplicationForm, "  startpos", http.StatusOK},
+		{echo.MIMEApplicationJSON, "sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1", http.StatusOK},
+		{echo.MIMEApplicationJSON, initialPosition, http.StatusOK},
+		{echo.MIMETextPlain, "startpos moves 7g7d", http.StatusBadRequest},
+		{echo.MIMEApplicationForm, "startpos moves " + strings.Repeat("7g7f 3c3d ", 1<<17), http.StatusBadRequest},
+	}
+
+	for i, c := range cases {
+		if status, b := post(c.contentType, c.body); status != c.status {
+			t.Errorf(`
+[app > server > handler > routes > /position/set]
+Index:    %d
+Expected: %d
+Actual:   %d %s
+`, i, c.status, status, b)
+		}
+	}
+
+	want := []string{
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1 moves 7g7f",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1",
+		"position sfen lnsgkgsnl/1r5b1/ppppppppp/9/9/9/PPPPPPPPP/1B5R1/LNSGKGSNL b - 1",
+	}
+	var positions []string
+	for _, r := range cmd.Received() {
+		if strings.HasPrefix(r, "position") {
+			positions = append(positions, r)
+		}
+	}
+	if strings.Join(positions, "\n") != strings.Join(want, "\n") {
+		t.Errorf(`
+[app > server > handler > routes > /position/set] unexpected positions
+Expected: %v
+Actual:   %v
+`, want, positions)
+	}
+}
@@ -5,13 +5,20 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"go.uber.org/zap"
 
 	"github.com/murosan/shogi-board-server/app/module"
 	"github.com/murosan/shogi-board-server/app/server/handler/routes"
@@ -25,6 +32,12 @@ var (
 		"application config path. default=config/app.config.yml",
 	)
 	logConfigPath = flag.String("log_config", "", "log config path. optional")
+
+	shutdownTimeout = flag.Duration(
+		"shutdown_timeout",
+		10*time.Second,
+		"max time to wait for in-flight requests, and for each engine to exit on shutdown",
+	)
 )
 
 func main() {
@@ -50,8 +63,42 @@ func main() {
 		module.Services.Engine,
 	)
 
-	err := e.Start(":" + *port)
-	if err != nil {
-		panic(err)
+	// streams of the result and events run until the client disconnects,
+	// so they are canceled with this context on shutdown
+	base, cancelStreams := context.WithCancel(context.Background())
+	e.Server.BaseContext = func(net.Listener) context.Context { return base }
+
+	go func() {
+		err := e.Start(":" + *port)
+		if err != nil && err != http.ErrServerClosed {
+			panic(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	module.Logger.Info("[Shutdown]", zap.String("signal", sig.String()))
+
+	shutdown(e, cancelStreams)
+}
+
+// shutdown ends the streams, stops accepting requests, waits for in-flight
+// requests, and then closes all engines, so that no engine process is left behind.
+func shutdown(e *echo.Echo, cancelStreams context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	cancelStreams()
+
+	if err := e.Shutdown(ctx); err != nil {
+		module.Logger.Error("[Shutdown] server", zap.Error(err))
+	}
+
+	// the error lists the engines which failed to exit
+	if err := module.Services.Engine.CloseAll(*shutdownTimeout); err != nil {
+		module.Logger.Error("[Shutdown] engines", zap.Error(err))
+		os.Exit(1)
 	}
+	module.Logger.Info("[Shutdown] all engines have exited")
 }
@@ -0,0 +1,41 @@
+// Command fakeengine is a fake shogi engine which speaks USI from a script,
+// for trying the server without real engine binaries.
+//
+//	go build -o fakeengine ./tools/fakeengine
+//	./fakeengine -script ./tools/fakeengine/script.example.json
+//
+// See app/domain/infrastructure/fake/engine.go about the script.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/murosan/shogi-board-server/app/domain/infrastructure/fake"
+)
+
+var scriptPath = flag.String("script", "", "script path (JSON). optional")
+
+func main() {
+	flag.Parse()
+
+	script := &fake.Script{
+		Name:      "fakeengine",
+		Author:    "fake",
+		Infos:     []string{"depth 1 score cp 0 pv 7g7f"},
+		BestMove:  "7g7f",
+		Checkmate: "nomate",
+	}
+
+	if *scriptPath != "" {
+		s, err := fake.LoadScript(*scriptPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		script = s
+	}
+
+	os.Exit(fake.NewEngine(script).Run(os.Stdin, os.Stdout, os.Stderr))
+}